@@ -0,0 +1,135 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+// Command rules is an offline helper for authoring match rules.
+//
+//	rules schema [-o rules.schema.json]             print the JSON Schema of the rules
+//	rules validate [-base-dir dir] <rules.json>...  report every problem found in rules files
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"matchmaking-function-grpc-plugin-server-go/pkg/server"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "schema":
+		err = runSchema(os.Args[2:])
+	case "validate":
+		err = runValidate(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		usage()
+
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage:
+  rules schema [-o file]      print the JSON Schema of the match rules
+  rules validate [-base-dir dir] <file>...
+                              validate rules files and print every problem found`)
+}
+
+func runSchema(args []string) error {
+	flags := flag.NewFlagSet("schema", flag.ExitOnError)
+	output := flags.String("o", "", "write the schema to this file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	schema, err := json.MarshalIndent(server.RulesJSONSchema(), "", "  ")
+	if err != nil {
+		return err
+	}
+	schema = append(schema, '\n')
+
+	if *output == "" {
+		_, err = os.Stdout.Write(schema)
+
+		return err
+	}
+
+	return os.WriteFile(*output, schema, 0o644)
+}
+
+func runValidate(args []string) error {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	baseDir := flags.String("base-dir", os.Getenv("RULES_BASE_DIR"), "directory of base rulesets referenced by \"extends\"")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		return fmt.Errorf("validate: no rules file given")
+	}
+
+	var bases server.BaseRulesets
+	if *baseDir != "" {
+		bases = server.RulesDirectory(*baseDir)
+	}
+
+	invalid := 0
+	for _, path := range flags.Args() {
+		if !validateFile(os.Stdout, path, bases) {
+			invalid++
+		}
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d rules files are invalid", invalid, flags.NArg())
+	}
+
+	return nil
+}
+
+// validateFile prints every problem found in a rules file and reports whether it is valid. Unknown fields are
+// reported, the match function would silently ignore them.
+func validateFile(out io.Writer, path string, bases server.BaseRulesets) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(out, "%s: %v\n", path, err)
+
+		return false
+	}
+
+	fromVersion, problems := server.CheckGameRules(string(data), bases)
+	if fromVersion > 0 && fromVersion < server.CurrentRulesVersion {
+		fmt.Fprintf(out, "%s: warning: rules version %d is deprecated, current version is %d\n",
+			path, fromVersion, server.CurrentRulesVersion)
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintf(out, "%s: %v\n", path, problem)
+	}
+
+	if len(problems) > 0 {
+		return false
+	}
+
+	fmt.Fprintf(out, "%s: ok\n", path)
+
+	return true
+}
@@ -11,7 +11,6 @@ import (
 	_ "net/http/pprof"
 	"os"
 
-	"net"
 	"net/http"
 	_ "net/http/pprof"
 	"os/signal"
@@ -49,16 +48,7 @@ import (
 )
 
 const (
-	environment     = "production"
-	id              = int64(1)
-	metricsEndpoint = "/metrics"
-	metricsPort     = 8080
-	grpcPort        = 6565
-)
-
-var (
-	serviceName = common.GetEnv("OTEL_SERVICE_NAME", "MatchmakingFunctionGrpcPluginServerGoDocker")
-	logLevelStr = common.GetEnv("LOG_LEVEL", "info")
+	id = int64(1)
 )
 
 func parseSlogLevel(levelStr string) slog.Level {
@@ -85,8 +75,14 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Parse log level from environment variable
-	slogLevel := parseSlogLevel(logLevelStr)
+	config, err := common.LoadConfig(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// Parse log level from the config
+	slogLevel := parseSlogLevel(config.LogLevel)
 
 	// Create JSON handler for structured logging
 	opts := &slog.HandlerOptions{
@@ -97,6 +93,7 @@ func main() {
 	slog.SetDefault(logger) // Set as default logger for the application
 
 	logger.Info("starting app server")
+	logger.Info("effective config", config.LogValues()...)
 
 	loggingOptions := []logging.Option{
 		logging.WithLogOnEvents(logging.StartCall, logging.FinishCall, logging.PayloadReceived, logging.PayloadSent),
@@ -153,10 +150,17 @@ func main() {
 		grpc.ChainStreamInterceptor(streamServerInterceptors...),
 	)
 
-	matchMaker := server.New()
-	matchfunctiongrpc.RegisterMatchFunctionServer(grpcServer, &server.MatchFunctionServer{
+	matchMaker := server.New(server.MatchMakerConfig{
+		RulesBaseDir:     config.RulesBaseDir,
+		MatchHistorySize: config.MatchHistorySize,
+		MatchHistoryTTL:  time.Duration(config.MatchHistoryTTLSeconds) * time.Second,
+	})
+	matchfunctiongrpc.RegisterMatchFunctionServer(grpcServer, &server.MatchFunctionServer[server.GameRules]{
 		UnimplementedMatchFunctionServer: matchfunctiongrpc.UnimplementedMatchFunctionServer{},
 		MM:                               matchMaker,
+		RulesCache:                       server.NewRulesCache[server.GameRules](config.RulesCacheSize),
+		TickCache:                        server.NewTickCache(config.TickCacheSize, time.Duration(config.TickCacheTTLSeconds)*time.Second),
+		TickBudget:                       time.Duration(config.TickBudgetMs) * time.Millisecond,
 	})
 
 	// Enable gRPC Reflection
@@ -174,18 +178,27 @@ func main() {
 		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
 		srvMetrics,
 	)
+	promRegistry.MustRegister(server.Collectors()...)
+
+	metricsListener, err := common.Listen(config.MetricsAddress)
+	if err != nil {
+		logger.Error("failed to listen for metrics", "error", err)
+		os.Exit(1)
+
+		return
+	}
 
 	go func() {
-		http.Handle(metricsEndpoint, promhttp.HandlerFor(promRegistry, promhttp.HandlerOpts{}))
-		if err := http.ListenAndServe(fmt.Sprintf(":%d", metricsPort), nil); err != nil {
+		http.Handle(config.MetricsPath, promhttp.HandlerFor(promRegistry, promhttp.HandlerOpts{}))
+		if err := http.Serve(metricsListener, nil); err != nil {
 			logger.Error("failed to serve metrics", "error", err)
 			os.Exit(1)
 		}
 	}()
-	logger.Info("prometheus metrics served at :8080/metrics")
+	logger.Info("prometheus metrics served", "address", metricsListener.Addr(), "path", config.MetricsPath)
 
-	logger.Info("listening to grpc port")
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", grpcPort))
+	logger.Info("listening to grpc address")
+	lis, err := common.Listen(config.GRPCAddress)
 	if err != nil {
 		logger.Error("failed to listen", "error", err)
 		os.Exit(1)
@@ -208,7 +221,7 @@ func main() {
 	logger.Info("starting init provider")
 
 	// Save Tracer Provider
-	tracerProvider, err := common.NewTracerProvider(serviceName, environment, id)
+	tracerProvider, err := common.NewTracerProvider(config.ServiceName, config.Environment, id)
 	if err != nil {
 		logger.Error("failed to create tracer provider", "error", err)
 		os.Exit(1)
@@ -235,8 +248,6 @@ func main() {
 		}
 	}(ctx)
 
-	flag.Parse()
-
 	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 	defer stop()
 	<-ctx.Done()
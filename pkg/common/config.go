@@ -0,0 +1,279 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"gopkg.in/yaml.v2"
+)
+
+// unixAddressPrefix marks a listen address as a Unix socket path, e.g. unix:///run/matchfunction.sock
+const unixAddressPrefix = "unix:"
+
+// Config holds the runtime settings of the server. They are read from defaults, then an optional YAML or JSON
+// file, then environment variables and finally command-line flags, each source overriding the previous ones.
+type Config struct {
+	// GRPCAddress is the gRPC listen address, host:port or unix:<path>
+	GRPCAddress string `json:"grpc_address" yaml:"grpc_address"`
+
+	// MetricsAddress is the Prometheus metrics listen address, host:port or unix:<path>
+	MetricsAddress string `json:"metrics_address" yaml:"metrics_address"`
+
+	// MetricsPath is the HTTP path the metrics are served at
+	MetricsPath string `json:"metrics_path" yaml:"metrics_path"`
+
+	// Environment is the deployment environment reported in the traces
+	Environment string `json:"environment" yaml:"environment"`
+
+	// ServiceName is the service name reported in the traces
+	ServiceName string `json:"service_name" yaml:"service_name"`
+
+	// LogLevel is debug, info, warn or error
+	LogLevel string `json:"log_level" yaml:"log_level"`
+
+	// RulesBaseDir is the directory of the base rulesets, empty disables "extends"
+	RulesBaseDir string `json:"rules_base_dir" yaml:"rules_base_dir"`
+
+	// RulesCacheSize is the number of parsed rulesets kept, 0 disables the cache
+	RulesCacheSize int `json:"rules_cache_size" yaml:"rules_cache_size"`
+
+	// TickCacheSize is the number of MakeMatches ticks replayed on retry, 0 disables the cache
+	TickCacheSize int `json:"tick_cache_size" yaml:"tick_cache_size"`
+
+	// TickCacheTTLSeconds is how long a tick is replayed on retry
+	TickCacheTTLSeconds int `json:"tick_cache_ttl_seconds" yaml:"tick_cache_ttl_seconds"`
+
+	// TickBudgetMs bounds the time spent on a tick, 0 disables the budget
+	TickBudgetMs int `json:"tick_budget_ms" yaml:"tick_budget_ms"`
+
+	// MatchHistorySize is the number of players and sessions kept in the match history, 0 disables it
+	MatchHistorySize int `json:"match_history_size" yaml:"match_history_size"`
+
+	// MatchHistoryTTLSeconds is how long the match history remembers a roster
+	MatchHistoryTTLSeconds int `json:"match_history_ttl_seconds" yaml:"match_history_ttl_seconds"`
+}
+
+// DefaultConfig returns the settings used when no source sets them
+func DefaultConfig() Config {
+	return Config{
+		GRPCAddress:    ":6565",
+		MetricsAddress: ":8080",
+		MetricsPath:    "/metrics",
+		Environment:    "production",
+		ServiceName:    "MatchmakingFunctionGrpcPluginServerGoDocker",
+		LogLevel:       "info",
+
+		RulesCacheSize:         256,
+		TickCacheSize:          1024,
+		TickCacheTTLSeconds:    300,
+		MatchHistorySize:       10000,
+		MatchHistoryTTLSeconds: 3600,
+	}
+}
+
+// configSetting binds a Config field, a *string or an *int, to its environment variable and command-line flag
+type configSetting struct {
+	field any
+	env   string
+	flag  string
+	usage string
+}
+
+func (c *Config) settings() []configSetting {
+	return []configSetting{
+		{&c.GRPCAddress, "GRPC_ADDRESS", "grpc-address", "gRPC listen address, host:port or unix:<path>"},
+		{&c.MetricsAddress, "METRICS_ADDRESS", "metrics-address", "Prometheus metrics listen address, host:port or unix:<path>"},
+		{&c.MetricsPath, "METRICS_PATH", "metrics-path", "HTTP path of the Prometheus metrics"},
+		{&c.Environment, "ENVIRONMENT", "environment", "deployment environment reported in the traces"},
+		{&c.ServiceName, "OTEL_SERVICE_NAME", "service-name", "service name reported in the traces"},
+		{&c.LogLevel, "LOG_LEVEL", "log-level", "log level: debug, info, warn or error"},
+		{&c.RulesBaseDir, "RULES_BASE_DIR", "rules-base-dir", "directory of the base rulesets"},
+		{&c.RulesCacheSize, "RULES_CACHE_SIZE", "rules-cache-size", "parsed rulesets kept, 0 disables the cache"},
+		{&c.TickCacheSize, "TICK_CACHE_SIZE", "tick-cache-size", "MakeMatches ticks replayed on retry, 0 disables the cache"},
+		{&c.TickCacheTTLSeconds, "TICK_CACHE_TTL_SECONDS", "tick-cache-ttl-seconds", "seconds a tick is replayed on retry"},
+		{&c.TickBudgetMs, "TICK_BUDGET_MS", "tick-budget-ms", "milliseconds spent on a tick at most, 0 disables the budget"},
+		{&c.MatchHistorySize, "MATCH_HISTORY_SIZE", "match-history-size", "players and sessions kept in the match history, 0 disables it"},
+		{&c.MatchHistoryTTLSeconds, "MATCH_HISTORY_TTL_SECONDS", "match-history-ttl-seconds", "seconds the match history remembers a roster"},
+	}
+}
+
+// set parses value into the field of the setting
+func (s configSetting) set(value string) error {
+	switch field := s.field.(type) {
+	case *string:
+		*field = value
+	case *int:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not an integer", s.flag, value)
+		}
+		*field = number
+	}
+
+	return nil
+}
+
+// value returns the current value of the field of the setting
+func (s configSetting) value() any {
+	switch field := s.field.(type) {
+	case *string:
+		return *field
+	case *int:
+		return *field
+	}
+
+	return nil
+}
+
+// LoadConfig registers the config flags on flags, parses args with it and returns the effective config.
+// The file is named by the -config flag or the CONFIG_FILE environment variable, it is decoded as JSON when its
+// extension is .json and as YAML otherwise.
+func LoadConfig(flags *flag.FlagSet, args []string) (Config, error) {
+	config := DefaultConfig()
+
+	file := flags.String("config", "", "YAML or JSON config file, overridden by environment variables and flags (env CONFIG_FILE)")
+	values := make(map[string]*string)
+	for _, setting := range config.settings() {
+		values[setting.flag] = flags.String(setting.flag, "", fmt.Sprintf("%s (env %s, default %q)", setting.usage, setting.env, fmt.Sprint(setting.value())))
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	path := *file
+	if path == "" {
+		path = GetEnv("CONFIG_FILE", "")
+	}
+	if path != "" {
+		if err := config.readFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	settings := config.settings()
+	for _, setting := range settings {
+		if value, ok := os.LookupEnv(setting.env); ok {
+			if err := setting.set(value); err != nil {
+				return Config{}, fmt.Errorf("invalid config: env %s: %w", setting.env, err)
+			}
+		}
+	}
+
+	var problems []error
+	flags.Visit(func(f *flag.Flag) {
+		for _, setting := range settings {
+			if f.Name == setting.flag {
+				if err := setting.set(*values[setting.flag]); err != nil {
+					problems = append(problems, err)
+				}
+			}
+		}
+	})
+	if len(problems) > 0 {
+		return Config{}, fmt.Errorf("invalid config: %w", errors.Join(problems...))
+	}
+
+	if err := config.validate(); err != nil {
+		return Config{}, err
+	}
+
+	return config, nil
+}
+
+// LogValues returns the effective settings as alternating slog keys and values, keyed by file field name
+func (c *Config) LogValues() []any {
+	var values []any
+	for _, setting := range c.settings() {
+		values = append(values, strings.ReplaceAll(setting.flag, "-", "_"), setting.value())
+	}
+
+	return values
+}
+
+// readFile overrides the config with the fields set in a YAML or JSON file, unknown fields are rejected
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(c)
+	} else {
+		err = yaml.UnmarshalStrict(data, c)
+	}
+	if err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (c Config) validate() error {
+	var problems []error
+
+	if c.GRPCAddress == "" {
+		problems = append(problems, fmt.Errorf("grpc_address: must not be empty"))
+	}
+	if c.MetricsAddress == "" {
+		problems = append(problems, fmt.Errorf("metrics_address: must not be empty"))
+	}
+	if !strings.HasPrefix(c.MetricsPath, "/") {
+		problems = append(problems, fmt.Errorf("metrics_path: must start with /"))
+	}
+	for _, setting := range c.settings() {
+		if value, ok := setting.value().(int); ok && value < 0 {
+			problems = append(problems, fmt.Errorf("%s: must not be negative", strings.ReplaceAll(setting.flag, "-", "_")))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(problems...))
+	}
+
+	return nil
+}
+
+// Listen listens on a host:port TCP address, or on a Unix socket for unix:<path> addresses. A socket file left
+// over by a previous run at the same path is removed first, one another process still accepts on is kept.
+func Listen(address string) (net.Listener, error) {
+	if !strings.HasPrefix(address, unixAddressPrefix) {
+		return net.Listen("tcp", address)
+	}
+
+	path := strings.TrimPrefix(strings.TrimPrefix(address, unixAddressPrefix), "//")
+	if info, err := os.Stat(path); err == nil && info.Mode().Type() == fs.ModeSocket {
+		connection, err := net.Dial("unix", path)
+		if err == nil {
+			connection.Close()
+
+			return nil, fmt.Errorf("socket %s is in use by another process", path)
+		}
+		if !errors.Is(err, syscall.ECONNREFUSED) {
+			return nil, fmt.Errorf("check socket %s: %w", path, err)
+		}
+
+		if err = os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale socket: %w", err)
+		}
+	}
+
+	return net.Listen("unix", path)
+}
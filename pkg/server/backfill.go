@@ -0,0 +1,464 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"matchmaking-function-grpc-plugin-server-go/pkg/common"
+	"matchmaking-function-grpc-plugin-server-go/pkg/matchmaker"
+	"matchmaking-function-grpc-plugin-server-go/pkg/playerdata"
+)
+
+// proposalAttributeSessionValue is the proposal attribute holding the average enriched stat of the seated players
+const proposalAttributeSessionValue = "session_value"
+
+// backfillSession is the partial match of a backfill ticket prepared for backfilling
+type backfillSession struct {
+	ticket   matchmaker.BackfillTicket
+	teams    []backfillTeam
+	value    float64         // average enriched stat of the seated players that have one
+	distance float64         // highest distance between the value of a joining ticket and the session value
+	ids      map[string]bool // IDs of the seated players
+	moved    map[int]int     // new team of the partial match tickets moved by the rebalancing, by ticket index
+
+	compatibility []map[string]bool // values the seated tickets agree on for each hard compatibility rule
+
+	constraints   teamConstraints  // character constraints the teams must keep
+	members       []matchCandidate // characters of the seated tickets, one per team a ticket has players on
+	memberTeams   []int            // team of each member
+	ticketMembers map[int]int      // member of each partial match ticket seated on a single team, by ticket index
+}
+
+// backfillTeam is a team of a partial match with the tickets proposed to join it
+type backfillTeam struct {
+	team    matchmaker.Team
+	players int
+	total   float64 // sum of the enriched stats of the seated players that have one
+	valued  int     // seated players that have an enriched stat
+	added   []int   // indexes of the candidates joining the team
+}
+
+// open returns the number of free seats of the team
+func (t backfillTeam) open(alliance AllianceRule) int {
+	return alliance.PlayerMaxNumber - t.players
+}
+
+// average returns the average enriched stat of the team, or fallback when no player of the team has one
+func (t backfillTeam) average(fallback float64) float64 {
+	if t.valued == 0 {
+		return fallback
+	}
+
+	return t.total / float64(t.valued)
+}
+
+// makeBackfillProposals fills the open seats of the partial matches of backfillTickets with the tickets whose
+// value is within the backfill distance of the session average and posts a proposal for every session that
+// gets new players. The tickets are assigned across every session at once by assignBackfill, so a ticket is
+// proposed to one session at most.
+func makeBackfillProposals(scope *common.Scope, backfillTickets []matchmaker.BackfillTicket, tickets []matchmaker.Ticket, rules GameRules, history *MatchHistory, results chan<- matchmaker.BackfillProposal) {
+	log := scope.Log.With("method", "makeBackfillProposals")
+
+	now := time.Now()
+	candidates := prepareCandidates(scope, tickets, rules, history, now)
+
+	// older sessions come first when assigning tickets
+	sort.SliceStable(backfillTickets, func(i, j int) bool {
+		return backfillTickets[i].CreatedAt.Before(backfillTickets[j].CreatedAt)
+	})
+
+	sessions := make([]*backfillSession, 0, len(backfillTickets))
+	for _, backfillTicket := range backfillTickets {
+		if session, ok := prepareSession(scope, backfillTicket, rules, now); ok {
+			sessions = append(sessions, session)
+		}
+	}
+
+	if !assignBackfill(scope, sessions, candidates, rules) {
+		log.Info("backfill cancelled", "proposals", 0)
+
+		return
+	}
+
+	made := 0
+	for _, session := range sessions {
+		if !session.joined() {
+			continue
+		}
+
+		if rules.Backfill.MovePlayers {
+			session.rebalance(candidates, rules)
+		}
+
+		select {
+		case results <- session.proposal(candidates, now):
+			ids := session.playerIDs(candidates)
+			history.RecordMatch(ids, now)
+			history.RecordSession(session.ticket.MatchSessionID, ids, now)
+			made++
+		case <-scope.Ctx.Done():
+			log.Info("backfill cancelled", "proposals", made)
+
+			return
+		}
+	}
+
+	log.Info("backfill finished", "backfillTickets", len(backfillTickets), "tickets", len(tickets), "proposals", made)
+}
+
+// prepareSession computes the team values of the partial match of a backfill ticket and adds the empty teams
+// the alliance still allows. It returns false when no seated player has an enriched stat to compare with.
+func prepareSession(scope *common.Scope, backfillTicket matchmaker.BackfillTicket, rules GameRules, now time.Time) (*backfillSession, bool) {
+	enrichedKey := rules.Statistics.GetEnrichedKey()
+	partial := backfillTicket.PartialMatch
+
+	values := make(map[string]float64)
+	session := &backfillSession{
+		ticket: backfillTicket,
+		ids:    make(map[string]bool),
+		moved:  make(map[int]int),
+
+		compatibility: make([]map[string]bool, len(rules.CompatibilityRules)),
+
+		constraints:   newTeamConstraints(rules, false),
+		ticketMembers: make(map[int]int),
+	}
+	// roles of the seated players are not known, only the character rules are kept
+	session.constraints.roles = RoleRule{}
+
+	for _, ticket := range partial.Tickets {
+		for _, player := range ticket.Players {
+			if value, ok := numericAttribute(player.Attributes[enrichedKey]); ok {
+				values[playerdata.IDToString(player.PlayerID)] = value
+			}
+		}
+
+		ticketValues := compatibilityValues(ticket, rules.CompatibilityRules)
+		for i, rule := range rules.CompatibilityRules {
+			if !rule.Soft && compatible(session.compatibility[i], ticketValues[i]) {
+				session.compatibility[i] = intersectValues(session.compatibility[i], ticketValues[i])
+			}
+		}
+	}
+
+	teamOf := make(map[string]int)
+	for i, team := range partial.Teams {
+		for _, userID := range team.UserIDs {
+			teamOf[playerdata.IDToString(userID)] = i
+		}
+	}
+	for index, ticket := range partial.Tickets {
+		session.addMembers(index, ticket, teamOf, rules.Statistics.GetSelectedStatKey())
+	}
+
+	total, valued := 0.0, 0
+	for _, team := range partial.Teams {
+		seated := backfillTeam{team: team, players: len(team.UserIDs)}
+		for _, userID := range team.UserIDs {
+			id := playerdata.IDToString(userID)
+			session.ids[id] = true
+
+			if value, ok := values[id]; ok {
+				seated.total += value
+				seated.valued++
+			}
+		}
+
+		total += seated.total
+		valued += seated.valued
+		session.teams = append(session.teams, seated)
+	}
+
+	if valued == 0 {
+		scope.Log.Warn("skipping backfill ticket without seated enriched values", "backfillTicketID", backfillTicket.TicketID, "key", enrichedKey)
+
+		return nil, false
+	}
+
+	for len(session.teams) < rules.Alliance.MaxNumber {
+		session.teams = append(session.teams, backfillTeam{team: matchmaker.Team{TeamID: common.GenerateUUID()}})
+	}
+
+	session.value = total / float64(valued)
+	session.distance = rules.Backfill.Distance
+	if session.distance == 0 {
+		session.distance = matchingDistanceAt(rules, now.Sub(backfillTicket.CreatedAt))
+	}
+
+	return session, true
+}
+
+// addMembers adds the characters of a seated ticket to the members of the teams its players sit on
+func (s *backfillSession) addMembers(index int, ticket matchmaker.Ticket, teamOf map[string]int, characterKey string) {
+	var teams []int
+	byTeam := make(map[int][]string)
+	for i, character := range playerCharacters(ticket, characterKey) {
+		team, ok := teamOf[playerdata.IDToString(ticket.Players[i].PlayerID)]
+		if !ok {
+			continue
+		}
+
+		if _, seen := byTeam[team]; !seen {
+			teams = append(teams, team)
+		}
+		byTeam[team] = append(byTeam[team], character)
+	}
+
+	if len(teams) == 1 {
+		s.ticketMembers[index] = len(s.members)
+	}
+	for _, team := range teams {
+		s.members = append(s.members, matchCandidate{characters: byTeam[team]})
+		s.memberTeams = append(s.memberTeams, team)
+	}
+}
+
+// fits reports whether team keeps the character constraints with the seated members on memberTeams and the
+// candidates at the indexes of added joining each team
+func (s *backfillSession) fits(candidates []matchCandidate, memberTeams []int, added [][]int, team int) bool {
+	if !s.constraints.active() {
+		return true
+	}
+
+	members := append([]matchCandidate(nil), s.members...)
+	teams := make([][]int, len(s.teams))
+	for member, seated := range memberTeams {
+		teams[seated] = append(teams[seated], member)
+	}
+	for joined, indexes := range added {
+		for _, index := range indexes {
+			teams[joined] = append(teams[joined], len(members))
+			members = append(members, candidates[index])
+		}
+	}
+
+	return s.constraints.teamFits(members, teams, team)
+}
+
+// added returns a copy of the indexes of the candidates joining each team
+func (s *backfillSession) added() [][]int {
+	added := make([][]int, len(s.teams))
+	for i, team := range s.teams {
+		added[i] = append([]int(nil), team.added...)
+	}
+
+	return added
+}
+
+// accepts reports whether candidate may join the session: its value is within the backfill distance, none of
+// its players is seated or avoids a seated player, it does not exclude the session and it agrees with the
+// session on the local DS, client version, region and hard compatibility rules
+func (s *backfillSession) accepts(candidate matchCandidate, rules GameRules) bool {
+	if math.Abs(candidate.value-s.value) > s.distance {
+		return false
+	}
+
+	for _, player := range candidate.ticket.Players {
+		if s.ids[playerdata.IDToString(player.PlayerID)] {
+			return false
+		}
+	}
+	for id := range candidate.avoid {
+		if s.ids[id] {
+			return false
+		}
+	}
+
+	if slices.Contains(candidate.ticket.ExcludedSessions, s.ticket.MatchSessionID) {
+		return false
+	}
+
+	partial := s.ticket.PartialMatch
+	if rules.ServerSelection.ServerNameAttribute != "" && candidate.serverName != partial.ServerName {
+		return false
+	}
+	if rules.ServerSelection.ClientVersionAttribute != "" && candidate.clientVersion != partial.ClientVersion {
+		return false
+	}
+
+	// tickets without latencies accept any region, the others must reach the region of the session
+	if candidate.regions != nil && len(partial.RegionPreference) > 0 {
+		if _, ok := candidate.regions[partial.RegionPreference[0]]; !ok {
+			return false
+		}
+	}
+
+	for i, rule := range rules.CompatibilityRules {
+		if !rule.Soft && !compatible(s.compatibility[i], candidate.compatibility[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// open returns the number of free seats of the session
+func (s *backfillSession) open(alliance AllianceRule) int {
+	open := 0
+	for _, team := range s.teams {
+		open += team.open(alliance)
+	}
+
+	return open
+}
+
+// seatTeam returns the team the candidate at index would join: the one with the fewest players that can seat
+// the whole ticket without breaking the character constraints, the lowest valued on ties. It returns -1 when
+// no team can seat it.
+func (s *backfillSession) seatTeam(candidates []matchCandidate, index int, alliance AllianceRule) int {
+	best := -1
+	for i, team := range s.teams {
+		if team.open(alliance) < candidates[index].players() {
+			continue
+		}
+
+		if best >= 0 && (team.players > s.teams[best].players ||
+			team.players == s.teams[best].players && team.average(s.value) >= s.teams[best].average(s.value)) {
+			continue
+		}
+
+		added := s.added()
+		added[i] = append(added[i], index)
+		if s.fits(candidates, s.memberTeams, added, i) {
+			best = i
+		}
+	}
+
+	return best
+}
+
+// clone returns a copy of the session whose teams can be seated without changing s
+func (s *backfillSession) clone() *backfillSession {
+	clone := *s
+	clone.teams = slices.Clone(s.teams)
+	for i := range clone.teams {
+		clone.teams[i].added = slices.Clone(s.teams[i].added)
+	}
+
+	return &clone
+}
+
+// seat adds the candidate at index to a team
+func (s *backfillSession) seat(candidates []matchCandidate, index, team int) {
+	candidate := candidates[index]
+
+	seated := &s.teams[team]
+	seated.added = append(seated.added, index)
+	seated.players += candidate.players()
+	seated.total += candidate.value * float64(candidate.players())
+	seated.valued += candidate.players()
+}
+
+// joined reports whether any candidate joins the session
+func (s *backfillSession) joined() bool {
+	for _, team := range s.teams {
+		if len(team.added) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// proposal returns the backfill proposal seating the candidates added to the session teams and the seated
+// tickets moved to another team. Teams that are still empty are left out.
+func (s *backfillSession) proposal(candidates []matchCandidate, now time.Time) matchmaker.BackfillProposal {
+	proposal := matchmaker.BackfillProposal{
+		BackfillTicketID: s.ticket.TicketID,
+		CreatedAt:        now,
+		ProposalID:       common.GenerateUUID(),
+		MatchPool:        s.ticket.MatchPool,
+		MatchSessionID:   s.ticket.MatchSessionID,
+		Attributes: map[string]interface{}{
+			proposalAttributeSessionValue: s.value,
+			matchAttributeTeamImbalance:   s.imbalance(s.teams),
+		},
+	}
+
+	// players of moved tickets leave their team and its parties, they are seated again on their new team
+	moving, movingParties := make(map[string]bool), make(map[string]bool)
+	moved := 0
+	for index := range s.moved {
+		ticket := s.ticket.PartialMatch.Tickets[index]
+		for _, player := range ticket.Players {
+			moving[playerdata.IDToString(player.PlayerID)] = true
+			moved++
+		}
+		if ticket.PartySessionID != "" {
+			movingParties[ticket.PartySessionID] = true
+		}
+	}
+	if moved > 0 {
+		proposal.Attributes[proposalAttributeMovedPlayers] = moved
+	}
+
+	for i, seated := range s.teams {
+		if seated.players == 0 {
+			continue
+		}
+
+		team := matchmaker.Team{TeamID: seated.team.TeamID}
+		for _, userID := range seated.team.UserIDs {
+			if !moving[playerdata.IDToString(userID)] {
+				team.UserIDs = append(team.UserIDs, userID)
+			}
+		}
+		for _, party := range seated.team.Parties {
+			if !movingParties[party.PartyID] && !slices.ContainsFunc(party.UserIDs, func(id string) bool { return moving[id] }) {
+				team.Parties = append(team.Parties, party)
+			}
+		}
+
+		for index, ticket := range s.ticket.PartialMatch.Tickets {
+			if to, ok := s.moved[index]; ok && to == i {
+				seatTicket(&team, ticket)
+			}
+		}
+
+		for _, index := range seated.added {
+			proposal.AddedTickets = append(proposal.AddedTickets, candidates[index].ticket)
+			seatTicket(&team, candidates[index].ticket)
+		}
+
+		proposal.ProposedTeams = append(proposal.ProposedTeams, team)
+	}
+
+	return proposal
+}
+
+// seatTicket adds the players of a ticket to a team, and its party when it has one
+func seatTicket(team *matchmaker.Team, ticket matchmaker.Ticket) {
+	userIDs := make([]string, 0, len(ticket.Players))
+	for _, player := range ticket.Players {
+		team.UserIDs = append(team.UserIDs, player.PlayerID)
+		userIDs = append(userIDs, playerdata.IDToString(player.PlayerID))
+	}
+
+	if ticket.PartySessionID != "" {
+		team.Parties = append(team.Parties, matchmaker.Party{PartyID: ticket.PartySessionID, UserIDs: userIDs})
+	}
+}
+
+// playerIDs returns the IDs of the seated players and of the players proposed to join
+func (s *backfillSession) playerIDs(candidates []matchCandidate) []string {
+	ids := make([]string, 0, len(s.ids))
+	for id := range s.ids {
+		ids = append(ids, id)
+	}
+
+	for _, team := range s.teams {
+		for _, index := range team.added {
+			for _, player := range candidates[index].ticket.Players {
+				ids = append(ids, playerdata.IDToString(player.PlayerID))
+			}
+		}
+	}
+
+	return ids
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"math"
+	"slices"
+	"sort"
+
+	"matchmaking-function-grpc-plugin-server-go/pkg/common"
+)
+
+// assignBackfill seats the candidates in the sessions accepting them, each candidate in one session at most.
+// sessions must be sorted oldest first. Parties are placed first, longest waiting first, in the oldest session
+// that can seat them whole. Solo tickets then take the remaining seats through augmenting paths, longest
+// waiting first: a solo takes a free seat of the oldest session accepting it, the seat of a solo that can
+// move to another session, or the seats of a party that can move whole to another session with room. A
+// party is moved only when that seats one more solo, so the filled seats never decrease, and no seated
+// solo is dropped for a later one. Within a session, the solos closest to the session value are seated first.
+// It returns false when scope.Ctx was done before the assignment was complete.
+func assignBackfill(scope *common.Scope, sessions []*backfillSession, candidates []matchCandidate, rules GameRules) bool {
+	// the sessions accepting each candidate, oldest first
+	accepted := make([][]int, len(candidates))
+	for c, candidate := range candidates {
+		if scope.Ctx.Err() != nil {
+			return false
+		}
+
+		for s, session := range sessions {
+			if session.accepts(candidate, rules) {
+				accepted[c] = append(accepted[c], s)
+			}
+		}
+	}
+
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return candidates[order[i]].ticket.CreatedAt.Before(candidates[order[j]].ticket.CreatedAt)
+	})
+
+	seats := make([]int, len(sessions))
+	for s, session := range sessions {
+		seats[s] = session.open(rules.Alliance)
+	}
+	parties := make([][]int, len(sessions))
+	solos := make([][]int, len(sessions))
+
+	// seatsParties reports whether session s can seat the parties, each whole on one team
+	seatsParties := func(s int, indexes []int) bool {
+		session := sessions[s].clone()
+		for _, c := range indexes {
+			team := session.seatTeam(candidates, c, rules.Alliance)
+			if team < 0 {
+				return false
+			}
+			session.seat(candidates, c, team)
+		}
+
+		return true
+	}
+
+	for _, c := range order {
+		if candidates[c].players() == 1 {
+			continue
+		}
+
+		for _, s := range accepted[c] {
+			if seats[s] >= candidates[c].players() && seatsParties(s, append(slices.Clone(parties[s]), c)) {
+				parties[s] = append(parties[s], c)
+				seats[s] -= candidates[c].players()
+
+				break
+			}
+		}
+	}
+
+	// moveParty moves a party of session s whole to another session accepting it that has room, freeing its seats.
+	// The parties left in s are seated again without it, so they must still fit.
+	moveParty := func(s int) bool {
+		for i, party := range parties[s] {
+			if !seatsParties(s, slices.Delete(slices.Clone(parties[s]), i, i+1)) {
+				continue
+			}
+
+			for _, other := range accepted[party] {
+				if other == s || seats[other] < candidates[party].players() || !seatsParties(other, append(slices.Clone(parties[other]), party)) {
+					continue
+				}
+
+				parties[s] = slices.Delete(parties[s], i, i+1)
+				parties[other] = append(parties[other], party)
+				seats[s] += candidates[party].players()
+				seats[other] -= candidates[party].players()
+
+				return true
+			}
+		}
+
+		return false
+	}
+
+	// augment seats candidate c, moving already assigned solos or parties to other sessions when needed
+	var augment func(c int, visited []bool) bool
+	augment = func(c int, visited []bool) bool {
+		for _, s := range accepted[c] {
+			if seats[s] > 0 {
+				solos[s] = append(solos[s], c)
+				seats[s]--
+
+				return true
+			}
+		}
+
+		for _, s := range accepted[c] {
+			if visited[s] {
+				continue
+			}
+			visited[s] = true
+
+			for i, other := range solos[s] {
+				if augment(other, visited) {
+					solos[s][i] = c
+
+					return true
+				}
+			}
+
+			if moveParty(s) {
+				solos[s] = append(solos[s], c)
+				seats[s]--
+
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for _, c := range order {
+		if candidates[c].players() != 1 || len(accepted[c]) == 0 {
+			continue
+		}
+
+		if scope.Ctx.Err() != nil {
+			return false
+		}
+
+		augment(c, make([]bool, len(sessions)))
+	}
+
+	for s, session := range sessions {
+		// the parties of a session were checked together, they are seated in the same order
+		for _, c := range parties[s] {
+			if team := session.seatTeam(candidates, c, rules.Alliance); team >= 0 {
+				session.seat(candidates, c, team)
+			}
+		}
+
+		sort.SliceStable(solos[s], func(i, j int) bool {
+			return math.Abs(candidates[solos[s][i]].value-session.value) < math.Abs(candidates[solos[s][j]].value-session.value)
+		})
+
+		// solos whose characters clash with every team with room are left out
+		for _, c := range solos[s] {
+			if team := session.seatTeam(candidates, c, rules.Alliance); team >= 0 {
+				session.seat(candidates, c, team)
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,261 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"matchmaking-function-grpc-plugin-server-go/pkg/common"
+	"matchmaking-function-grpc-plugin-server-go/pkg/matchmaker"
+	"matchmaking-function-grpc-plugin-server-go/pkg/playerdata"
+)
+
+// testScope returns a scope that discards its logs
+func testScope() *common.Scope {
+	return &common.Scope{Ctx: context.Background(), Log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+}
+
+// backfillTestTicket returns a ticket of one player per character, all valued at value, created waited ago.
+// Empty characters leave the player without one.
+func backfillTestTicket(id string, value float64, waited time.Duration, characters ...string) matchmaker.Ticket {
+	var rules GameRules
+	ticket := matchmaker.Ticket{TicketID: id, CreatedAt: time.Now().Add(-waited)}
+	for i, character := range characters {
+		attributes := map[string]interface{}{rules.Statistics.GetEnrichedKey(): value}
+		if character != "" {
+			attributes[rules.Statistics.GetSelectedStatKey()] = character
+		}
+
+		ticket.Players = append(ticket.Players, playerdata.PlayerData{
+			PlayerID:   playerdata.IDFromString(fmt.Sprintf("%s-%d", id, i)),
+			Attributes: attributes,
+		})
+	}
+
+	if len(ticket.Players) > 1 {
+		ticket.PartySessionID = "party-" + id
+	}
+
+	return ticket
+}
+
+// backfillTestSession returns a backfill ticket for session id created waited ago, with one team per list of
+// seated tickets
+func backfillTestSession(id string, waited time.Duration, teams ...[]matchmaker.Ticket) matchmaker.BackfillTicket {
+	backfillTicket := matchmaker.BackfillTicket{TicketID: "backfill-" + id, MatchSessionID: id, CreatedAt: time.Now().Add(-waited)}
+	for i, tickets := range teams {
+		team := matchmaker.Team{TeamID: fmt.Sprintf("%s-team-%d", id, i)}
+		for _, ticket := range tickets {
+			backfillTicket.PartialMatch.Tickets = append(backfillTicket.PartialMatch.Tickets, ticket)
+			seatTicket(&team, ticket)
+		}
+		backfillTicket.PartialMatch.Teams = append(backfillTicket.PartialMatch.Teams, team)
+	}
+
+	return backfillTicket
+}
+
+// backfillTestRules returns rules backfilling teams of players players within a distance of 1000
+func backfillTestRules(teams, players int) GameRules {
+	return GameRules{
+		Alliance: AllianceRule{MinNumber: 1, MaxNumber: teams, PlayerMinNumber: 1, PlayerMaxNumber: players},
+		Backfill: BackfillRule{Distance: 1000},
+	}
+}
+
+// runAssignment prepares the sessions and candidates the way makeBackfillProposals does and assigns them
+func runAssignment(t *testing.T, rules GameRules, backfillTickets []matchmaker.BackfillTicket, tickets []matchmaker.Ticket) ([]*backfillSession, []matchCandidate) {
+	t.Helper()
+
+	scope := testScope()
+	now := time.Now()
+	candidates := prepareCandidates(scope, tickets, rules, nil, now)
+
+	sort.SliceStable(backfillTickets, func(i, j int) bool {
+		return backfillTickets[i].CreatedAt.Before(backfillTickets[j].CreatedAt)
+	})
+
+	var sessions []*backfillSession
+	for _, backfillTicket := range backfillTickets {
+		if session, ok := prepareSession(scope, backfillTicket, rules, now); ok {
+			sessions = append(sessions, session)
+		}
+	}
+
+	if !assignBackfill(scope, sessions, candidates, rules) {
+		t.Fatal("assignBackfill was cancelled")
+	}
+
+	return sessions, candidates
+}
+
+// joiningTickets returns the IDs of the tickets joining the session
+func joiningTickets(session *backfillSession, candidates []matchCandidate) []string {
+	var ids []string
+	for _, team := range session.teams {
+		for _, index := range team.added {
+			ids = append(ids, candidates[index].ticket.TicketID)
+		}
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// checkAssignment fails the test when a ticket joins two sessions, a team is over capacity or a team has a
+// character twice, and returns the number of players joining
+func checkAssignment(t *testing.T, rules GameRules, sessions []*backfillSession, candidates []matchCandidate) int {
+	t.Helper()
+
+	joined, players := make(map[int]string), 0
+	for _, session := range sessions {
+		for i, team := range session.teams {
+			if team.players > rules.Alliance.PlayerMaxNumber {
+				t.Errorf("session %s team %d has %d players, more than %d", session.ticket.MatchSessionID, i, team.players, rules.Alliance.PlayerMaxNumber)
+			}
+
+			var characters []string
+			for member, seated := range session.memberTeams {
+				if seated == i {
+					characters = append(characters, session.members[member].characters...)
+				}
+			}
+
+			for _, index := range team.added {
+				if other, ok := joined[index]; ok {
+					t.Errorf("ticket %s joins sessions %s and %s", candidates[index].ticket.TicketID, other, session.ticket.MatchSessionID)
+				}
+				joined[index] = session.ticket.MatchSessionID
+				players += candidates[index].players()
+				characters = append(characters, candidates[index].characters...)
+			}
+
+			if rules.Characters.UniquePerTeam && hasDuplicateCharacter(characters) {
+				t.Errorf("session %s team %d has a character twice: %v", session.ticket.MatchSessionID, i, characters)
+			}
+		}
+	}
+
+	return players
+}
+
+func TestAssignBackfillSeatsEachTicketOnce(t *testing.T) {
+	rules := backfillTestRules(2, 3)
+
+	var backfillTickets []matchmaker.BackfillTicket
+	for i := range 3 {
+		id := fmt.Sprintf("session-%d", i)
+		backfillTickets = append(backfillTickets, backfillTestSession(id, time.Minute, []matchmaker.Ticket{backfillTestTicket(id+"-seated", 1000, time.Minute, "")}))
+	}
+
+	var tickets []matchmaker.Ticket
+	for i := range 20 {
+		tickets = append(tickets, backfillTestTicket(fmt.Sprintf("ticket-%d", i), 1000, time.Duration(i)*time.Second, ""))
+	}
+
+	sessions, candidates := runAssignment(t, rules, backfillTickets, tickets)
+
+	// each session has 5 open seats
+	if players := checkAssignment(t, rules, sessions, candidates); players != 15 {
+		t.Errorf("%d players joined, want 15", players)
+	}
+}
+
+func TestAssignBackfillPrefersOlderSessionsAndLongerWaitingTickets(t *testing.T) {
+	rules := backfillTestRules(1, 2)
+
+	backfillTickets := []matchmaker.BackfillTicket{
+		backfillTestSession("newer", time.Second, []matchmaker.Ticket{backfillTestTicket("newer-seated", 1000, time.Second, "")}),
+		backfillTestSession("older", time.Minute, []matchmaker.Ticket{backfillTestTicket("older-seated", 1000, time.Minute, "")}),
+	}
+	tickets := []matchmaker.Ticket{
+		backfillTestTicket("recent", 1000, time.Second, ""),
+		backfillTestTicket("oldest", 1000, time.Minute, ""),
+		backfillTestTicket("waiting", 1000, 30*time.Second, ""),
+	}
+
+	sessions, candidates := runAssignment(t, rules, backfillTickets, tickets)
+	checkAssignment(t, rules, sessions, candidates)
+
+	got := map[string][]string{}
+	for _, session := range sessions {
+		got[session.ticket.MatchSessionID] = joiningTickets(session, candidates)
+	}
+
+	if fmt.Sprint(got["older"]) != "[oldest]" || fmt.Sprint(got["newer"]) != "[waiting]" {
+		t.Errorf("older session got %v and newer session got %v, want [oldest] and [waiting]", got["older"], got["newer"])
+	}
+}
+
+func TestAssignBackfillMovesPartiesToFillMoreSeats(t *testing.T) {
+	rules := backfillTestRules(1, 3)
+
+	backfillTickets := []matchmaker.BackfillTicket{
+		backfillTestSession("a", time.Minute, []matchmaker.Ticket{backfillTestTicket("a-seated", 1000, time.Minute, "")}),
+		backfillTestSession("b", time.Second, []matchmaker.Ticket{backfillTestTicket("b-seated", 1000, time.Second, "")}),
+	}
+
+	// the party is placed first in the oldest session, the solos only accept that one
+	party := backfillTestTicket("party", 1000, time.Hour, "", "")
+	first, second := backfillTestTicket("first", 1000, time.Second, ""), backfillTestTicket("second", 1000, time.Second, "")
+	first.ExcludedSessions, second.ExcludedSessions = []string{"b"}, []string{"b"}
+
+	sessions, candidates := runAssignment(t, rules, backfillTickets, []matchmaker.Ticket{party, first, second})
+
+	if players := checkAssignment(t, rules, sessions, candidates); players != 4 {
+		t.Errorf("%d players joined, want all 4", players)
+	}
+}
+
+// TestAssignBackfillKeepsCharacterRules moves parties out of the oldest session to seat solos that only accept
+// it, the parties left there must still be seated without a duplicate character
+func TestAssignBackfillKeepsCharacterRules(t *testing.T) {
+	characters := []string{"a", "b", "c", "d", "e", "f"}
+
+	for seed := range int64(500) {
+		random := rand.New(rand.NewSource(seed))
+		rules := backfillTestRules(3, 5)
+		rules.Characters.UniquePerTeam = true
+
+		// randomCharacters returns count distinct characters
+		randomCharacters := func(count int) []string {
+			picked := append([]string(nil), characters...)
+			random.Shuffle(len(picked), func(i, j int) { picked[i], picked[j] = picked[j], picked[i] })
+
+			return picked[:count]
+		}
+
+		backfillTickets := []matchmaker.BackfillTicket{
+			backfillTestSession("older", time.Minute, []matchmaker.Ticket{backfillTestTicket("older-seated", 1000, time.Minute, randomCharacters(1)...)}),
+			backfillTestSession("newer", time.Second, []matchmaker.Ticket{backfillTestTicket("newer-seated", 1000, time.Second, randomCharacters(1)...)}),
+		}
+
+		var tickets []matchmaker.Ticket
+		for i := range 3 + random.Intn(3) {
+			party := backfillTestTicket(fmt.Sprintf("party-%d", i), 1000, time.Hour-time.Duration(i)*time.Minute, randomCharacters(1+random.Intn(3))...)
+			if random.Intn(2) == 0 {
+				party.ExcludedSessions = []string{"newer"}
+			}
+			tickets = append(tickets, party)
+		}
+		for i := range 3 + random.Intn(8) {
+			solo := backfillTestTicket(fmt.Sprintf("solo-%d", i), 1000, time.Duration(i)*time.Second, randomCharacters(1)...)
+			solo.ExcludedSessions = []string{"newer"}
+			tickets = append(tickets, solo)
+		}
+
+		t.Run(fmt.Sprintf("seed %d", seed), func(t *testing.T) {
+			sessions, candidates := runAssignment(t, rules, backfillTickets, tickets)
+			checkAssignment(t, rules, sessions, candidates)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"math"
+
+	"matchmaking-function-grpc-plugin-server-go/pkg/playerdata"
+)
+
+// proposalAttributeMovedPlayers is the proposal attribute holding the number of seated players moved to another team
+const proposalAttributeMovedPlayers = "moved_players"
+
+// backfillUnit is a ticket the rebalancing may move between the teams of a session, never split
+type backfillUnit struct {
+	team    int
+	players int
+	total   float64
+	valued  int
+
+	seated int // index of the ticket in the partial match, -1 for a candidate joining the session
+	added  int // index of the joining candidate, -1 for a seated ticket
+}
+
+// rebalance moves whole tickets, seated or joining, between the session teams as long as a move or a swap of
+// two tickets lowers the team imbalance without breaking the character constraints. Seated tickets whose
+// players sit on different teams stay in place and no team that has players is emptied. Joining tickets end
+// up in the added lists of their final team and seated tickets that changed team are recorded in s.moved.
+func (s *backfillSession) rebalance(candidates []matchCandidate, rules GameRules) {
+	enrichedKey := rules.Statistics.GetEnrichedKey()
+
+	seatedTeam := make(map[string]int)
+	for i, team := range s.teams {
+		for _, userID := range team.team.UserIDs {
+			seatedTeam[playerdata.IDToString(userID)] = i
+		}
+	}
+
+	var units []backfillUnit
+	for i, ticket := range s.ticket.PartialMatch.Tickets {
+		unit := backfillUnit{team: -1, players: len(ticket.Players), seated: i, added: -1}
+		for _, player := range ticket.Players {
+			team, ok := seatedTeam[playerdata.IDToString(player.PlayerID)]
+			if !ok || unit.team >= 0 && team != unit.team {
+				unit.team = -1
+
+				break
+			}
+			unit.team = team
+
+			if value, ok := numericAttribute(player.Attributes[enrichedKey]); ok {
+				unit.total += value
+				unit.valued++
+			}
+		}
+
+		if unit.team >= 0 {
+			units = append(units, unit)
+		}
+	}
+
+	for i, team := range s.teams {
+		for _, index := range team.added {
+			candidate := candidates[index]
+			units = append(units, backfillUnit{
+				team:    i,
+				players: candidate.players(),
+				total:   candidate.value * float64(candidate.players()),
+				valued:  candidate.players(),
+				seated:  -1,
+				added:   index,
+			})
+		}
+	}
+
+	teams := make([]backfillTeam, len(s.teams))
+	copy(teams, s.teams)
+
+	move := func(unit *backfillUnit, to int) {
+		from := &teams[unit.team]
+		from.players -= unit.players
+		from.total -= unit.total
+		from.valued -= unit.valued
+
+		teams[to].players += unit.players
+		teams[to].total += unit.total
+		teams[to].valued += unit.valued
+		unit.team = to
+	}
+
+	// fits checks the character constraints of a team with the units where they currently are
+	fits := func(team int) bool {
+		memberTeams := append([]int(nil), s.memberTeams...)
+		added := make([][]int, len(teams))
+		for _, unit := range units {
+			if unit.added >= 0 {
+				added[unit.team] = append(added[unit.team], unit.added)
+			} else if member, ok := s.ticketMembers[unit.seated]; ok {
+				memberTeams[member] = unit.team
+			}
+		}
+
+		return s.fits(candidates, memberTeams, added, team)
+	}
+
+	// each accepted change strictly lowers the imbalance, the bound only guards against rounding loops
+	for range len(units) * len(teams) {
+		best := s.imbalance(teams)
+		bestUnit, bestOther, bestTeam := -1, -1, -1
+
+		for u := range units {
+			from := units[u].team
+
+			for to := range teams {
+				if to == from || teams[to].players+units[u].players > rules.Alliance.PlayerMaxNumber || teams[from].players == units[u].players {
+					continue
+				}
+
+				move(&units[u], to)
+				if imbalance := s.imbalance(teams); imbalance < best && fits(to) {
+					best, bestUnit, bestOther, bestTeam = imbalance, u, -1, to
+				}
+				move(&units[u], from)
+			}
+
+			for v := u + 1; v < len(units); v++ {
+				to := units[v].team
+				if to == from ||
+					teams[to].players-units[v].players+units[u].players > rules.Alliance.PlayerMaxNumber ||
+					teams[from].players-units[u].players+units[v].players > rules.Alliance.PlayerMaxNumber {
+					continue
+				}
+
+				move(&units[u], to)
+				move(&units[v], from)
+				if imbalance := s.imbalance(teams); imbalance < best && fits(to) && fits(from) {
+					best, bestUnit, bestOther, bestTeam = imbalance, u, v, to
+				}
+				move(&units[v], to)
+				move(&units[u], from)
+			}
+		}
+
+		if bestUnit < 0 {
+			break
+		}
+
+		from := units[bestUnit].team
+		move(&units[bestUnit], bestTeam)
+		if bestOther >= 0 {
+			move(&units[bestOther], from)
+		}
+	}
+
+	for i := range teams {
+		teams[i].added = nil
+	}
+	for _, unit := range units {
+		if unit.added >= 0 {
+			teams[unit.team].added = append(teams[unit.team].added, unit.added)
+		} else if seatedTeam[playerdata.IDToString(s.ticket.PartialMatch.Tickets[unit.seated].Players[0].PlayerID)] != unit.team {
+			s.moved[unit.seated] = unit.team
+		}
+	}
+
+	s.teams = teams
+}
+
+// imbalance returns the difference between the highest and lowest average of the teams that have players
+func (s *backfillSession) imbalance(teams []backfillTeam) float64 {
+	highest, lowest := math.Inf(-1), math.Inf(1)
+	for _, team := range teams {
+		if team.players == 0 {
+			continue
+		}
+
+		value := team.average(s.value)
+		highest, lowest = math.Max(highest, value), math.Min(lowest, value)
+	}
+
+	if math.IsInf(highest, -1) {
+		return 0
+	}
+
+	return highest - lowest
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"matchmaking-function-grpc-plugin-server-go/pkg/matchmaker"
+	"matchmaking-function-grpc-plugin-server-go/pkg/playerdata"
+)
+
+// runBackfill returns the proposals makeBackfillProposals posts
+func runBackfill(rules GameRules, backfillTickets []matchmaker.BackfillTicket, tickets []matchmaker.Ticket) []matchmaker.BackfillProposal {
+	results := make(chan matchmaker.BackfillProposal, len(backfillTickets))
+	makeBackfillProposals(testScope(), backfillTickets, tickets, rules, nil, results)
+	close(results)
+
+	var proposals []matchmaker.BackfillProposal
+	for proposal := range results {
+		proposals = append(proposals, proposal)
+	}
+
+	return proposals
+}
+
+// proposedTeams returns the ID of the proposed team of every player
+func proposedTeams(proposal matchmaker.BackfillProposal) map[string]string {
+	teams := make(map[string]string)
+	for _, team := range proposal.ProposedTeams {
+		for _, userID := range team.UserIDs {
+			teams[playerdata.IDToString(userID)] = team.TeamID
+		}
+	}
+
+	return teams
+}
+
+func TestRebalanceMovesSeatedPlayersOnlyWhenAllowed(t *testing.T) {
+	// the strong players all sit on the first team, swapping one of them lowers the imbalance
+	strong := []matchmaker.Ticket{backfillTestTicket("strong-1", 2000, time.Minute, ""), backfillTestTicket("strong-2", 2000, time.Minute, "")}
+	weak := []matchmaker.Ticket{backfillTestTicket("weak-1", 1000, time.Minute, ""), backfillTestTicket("weak-2", 1000, time.Minute, "")}
+	backfillTicket := backfillTestSession("session", time.Minute, strong, weak)
+	joining := []matchmaker.Ticket{backfillTestTicket("joining", 1500, time.Second, "")}
+
+	for _, movePlayers := range []bool{false, true} {
+		t.Run(fmt.Sprintf("move_players %v", movePlayers), func(t *testing.T) {
+			rules := backfillTestRules(2, 3)
+			rules.Backfill.MovePlayers = movePlayers
+
+			proposals := runBackfill(rules, []matchmaker.BackfillTicket{backfillTicket}, joining)
+			if len(proposals) != 1 {
+				t.Fatalf("%d proposals, want 1", len(proposals))
+			}
+
+			teams := proposedTeams(proposals[0])
+			stayed := true
+			for i, seated := range backfillTicket.PartialMatch.Teams {
+				for _, userID := range seated.UserIDs {
+					stayed = stayed && teams[playerdata.IDToString(userID)] == backfillTicket.PartialMatch.Teams[i].TeamID
+				}
+			}
+
+			_, moved := proposals[0].Attributes[proposalAttributeMovedPlayers]
+			if !movePlayers && (!stayed || moved) {
+				t.Errorf("seated players were moved without move_players: %v", proposals[0].ProposedTeams)
+			}
+			if movePlayers && (stayed || !moved) {
+				t.Errorf("no seated player was moved with move_players: %v", proposals[0].ProposedTeams)
+			}
+		})
+	}
+}
+
+func TestRebalanceKeepsPartiesWholeAndOnlyLowersTheImbalance(t *testing.T) {
+	for seed := range int64(300) {
+		random := rand.New(rand.NewSource(seed))
+		rules := backfillTestRules(2+random.Intn(2), 4)
+		rules.Backfill.MovePlayers = true
+
+		// randomTicket returns a ticket of 1 to 3 players around 1500
+		randomTicket := func(id string, waited time.Duration) matchmaker.Ticket {
+			return backfillTestTicket(id, float64(1000+random.Intn(1000)), waited, make([]string, 1+random.Intn(3))...)
+		}
+
+		var teams [][]matchmaker.Ticket
+		for i := range 2 {
+			var seated []matchmaker.Ticket
+			for players, j := 0, 0; j < 2; j++ {
+				ticket := randomTicket(fmt.Sprintf("seated-%d-%d", i, j), time.Minute)
+				if players+len(ticket.Players) > rules.Alliance.PlayerMaxNumber {
+					break
+				}
+				players += len(ticket.Players)
+				seated = append(seated, ticket)
+			}
+			teams = append(teams, seated)
+		}
+		backfillTicket := backfillTestSession("session", time.Minute, teams...)
+
+		var tickets []matchmaker.Ticket
+		for i := range 1 + random.Intn(4) {
+			tickets = append(tickets, randomTicket(fmt.Sprintf("joining-%d", i), time.Duration(i)*time.Second))
+		}
+
+		t.Run(fmt.Sprintf("seed %d", seed), func(t *testing.T) {
+			sessions, candidates := runAssignment(t, rules, []matchmaker.BackfillTicket{backfillTicket}, tickets)
+			session := sessions[0]
+			if !session.joined() {
+				return
+			}
+
+			before := session.imbalance(session.teams)
+			seatedAdded := make([][]int, len(session.teams))
+			for i, team := range session.teams {
+				seatedAdded[i] = team.added
+			}
+
+			session.rebalance(candidates, rules)
+
+			after := session.imbalance(session.teams)
+			changed := len(session.moved) > 0
+			for i, team := range session.teams {
+				changed = changed || fmt.Sprint(team.added) != fmt.Sprint(seatedAdded[i])
+			}
+			if after > before || changed && after >= before {
+				t.Errorf("imbalance went from %v to %v, moved %v", before, after, session.moved)
+			}
+
+			checkAssignment(t, rules, sessions, candidates)
+
+			// every ticket, seated or joining, ends up on one team
+			proposed := proposedTeams(session.proposal(candidates, time.Now()))
+			for _, ticket := range append(backfillTicket.PartialMatch.Tickets, tickets...) {
+				team, ok := proposed[playerdata.IDToString(ticket.Players[0].PlayerID)]
+				for _, player := range ticket.Players[1:] {
+					if other := proposed[playerdata.IDToString(player.PlayerID)]; ok && other != team {
+						t.Errorf("ticket %s is split across teams %s and %s", ticket.TicketID, team, other)
+					}
+				}
+			}
+		})
+	}
+}
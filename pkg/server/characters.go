@@ -0,0 +1,75 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"matchmaking-function-grpc-plugin-server-go/pkg/matchmaker"
+)
+
+const (
+	mirrorMatchAllow    = "allow"
+	mirrorMatchForbid   = "forbid"
+	mirrorMatchPenalize = "penalize"
+)
+
+// playerCharacters returns the character of each ticket player, the stat they selected, in player order.
+// Players without a selected stat have an empty character, which never conflicts.
+func playerCharacters(ticket matchmaker.Ticket, key string) []string {
+	characters := make([]string, len(ticket.Players))
+	for i, player := range ticket.Players {
+		characters[i], _ = player.Attributes[key].(string)
+	}
+
+	return characters
+}
+
+// hasDuplicateCharacter reports whether a character appears twice in the list
+func hasDuplicateCharacter(characters []string) bool {
+	seen := make(map[string]bool, len(characters))
+	for _, character := range characters {
+		if character == "" {
+			continue
+		}
+		if seen[character] {
+			return true
+		}
+		seen[character] = true
+	}
+
+	return false
+}
+
+// teamCharacters returns the characters of every player of a team
+func teamCharacters(members []matchCandidate, team []int) []string {
+	var characters []string
+	for _, member := range team {
+		characters = append(characters, members[member].characters...)
+	}
+
+	return characters
+}
+
+// mirrorConflict reports whether a character of the team is also played on another team
+func mirrorConflict(members []matchCandidate, teams [][]int, team int) bool {
+	own := make(map[string]bool)
+	for _, character := range teamCharacters(members, teams[team]) {
+		if character != "" {
+			own[character] = true
+		}
+	}
+
+	for other := range teams {
+		if other == team {
+			continue
+		}
+		for _, character := range teamCharacters(members, teams[other]) {
+			if own[character] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
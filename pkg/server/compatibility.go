@@ -0,0 +1,92 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"encoding/json"
+
+	"matchmaking-function-grpc-plugin-server-go/pkg/matchmaker"
+)
+
+const (
+	compatibilityEqual     = "equal"
+	compatibilityIntersect = "intersect"
+)
+
+// compatibilityValues returns the value set of every compatibility rule for a ticket, in rule order.
+// An "equal" attribute is a set of one value, an "intersect" attribute the set of its list elements.
+// The set is nil, compatible with any ticket, when the ticket does not set the attribute.
+func compatibilityValues(ticket matchmaker.Ticket, rules []CompatibilityRule) []map[string]bool {
+	values := make([]map[string]bool, len(rules))
+	for i, rule := range rules {
+		raw, ok := ticket.TicketAttributes[rule.Attribute]
+		if !ok || raw == nil {
+			continue
+		}
+
+		switch v := raw.(type) {
+		case []interface{}:
+			if rule.Criteria == compatibilityIntersect && len(v) > 0 {
+				values[i] = make(map[string]bool, len(v))
+				for _, item := range v {
+					values[i][attributeValueKey(item)] = true
+				}
+
+				continue
+			}
+		case []string:
+			if rule.Criteria == compatibilityIntersect && len(v) > 0 {
+				values[i] = make(map[string]bool, len(v))
+				for _, item := range v {
+					values[i][item] = true
+				}
+
+				continue
+			}
+		}
+
+		values[i] = map[string]bool{attributeValueKey(raw): true}
+	}
+
+	return values
+}
+
+// attributeValueKey returns a comparable form of an attribute value
+func attributeValueKey(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+
+	return string(encoded)
+}
+
+// intersectValues returns the values present in both sets, a nil set accepts any value
+func intersectValues(a, b map[string]bool) map[string]bool {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+
+	values := make(map[string]bool)
+	for value := range a {
+		if b[value] {
+			values[value] = true
+		}
+	}
+
+	return values
+}
+
+// compatible reports whether two value sets share a value, a nil set accepts any value
+func compatible(a, b map[string]bool) bool {
+	return a == nil || b == nil || len(intersectValues(a, b)) > 0
+}
@@ -4,22 +4,40 @@
 
 package server
 
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// CurrentRulesVersion is the rules schema version RulesFromJSON migrates every ruleset to
+const CurrentRulesVersion = 2
+
+// StatDefinition describes a single stat code players can select
+type StatDefinition struct {
+	// Code is the stat code (e.g., "mmr_ryu")
+	Code string `json:"code" description:"Stat code players can select, e.g. mmr_ryu"`
+}
+
 // StatisticsConfig holds configuration for statistic-based matchmaking
 type StatisticsConfig struct {
-	// Statistics is the list of valid stat codes (e.g., ["mmr_ryu", "mmr_ken", "rank_score"])
-	Statistics []string `json:"statistics"`
+	// Statistics is the list of valid stats (e.g., [{"code": "mmr_ryu"}, {"code": "rank_score"}])
+	// Version 1 rulesets used a flat list of stat codes, see migrateFlatStatistics
+	Statistics []StatDefinition `json:"statistics" description:"Stats players can select for matchmaking"`
 
-	// SelectedStatKey is the attribute key players use to specify which stat to use
+	// SelectedStatKey is the player attribute EnrichTicket writes the selected stat code to, it stands for the
+	// player's character in the character rules. Players select a stat under their player ID in the ticket attributes.
 	// Default: "selected_stat"
-	SelectedStatKey string `json:"selected_stat_key"`
+	SelectedStatKey string `json:"selected_stat_key" description:"Player attribute key the selected stat code is kept under after enrichment" default:"selected_stat"`
 
 	// EnrichedKey is the ticket attribute key where the selected stat value is stored after enrichment
 	// Default: "mmr"
-	EnrichedKey string `json:"enriched_key"`
+	EnrichedKey string `json:"enriched_key" description:"Player attribute key the selected stat value is stored under after enrichment" default:"mmr"`
 
 	// DefaultValue is the value to use if player doesn't have the selected stat
 	// If 0, validation will fail for missing stat
-	DefaultValue float64 `json:"default_value"`
+	DefaultValue float64 `json:"default_value" description:"Value to use if a player doesn't have the selected stat" default:"0"`
 }
 
 // GetSelectedStatKey returns the key for selected stat, defaulting to "selected_stat"
@@ -40,10 +58,20 @@ func (c StatisticsConfig) GetEnrichedKey() string {
 	return c.EnrichedKey
 }
 
+// StatCodes returns the configured stat codes in order
+func (c StatisticsConfig) StatCodes() []string {
+	codes := make([]string, 0, len(c.Statistics))
+	for _, stat := range c.Statistics {
+		codes = append(codes, stat.Code)
+	}
+
+	return codes
+}
+
 // IsValidStat checks if a stat code is in the allowed list
 func (c StatisticsConfig) IsValidStat(statCode string) bool {
 	for _, validStat := range c.Statistics {
-		if validStat == statCode {
+		if validStat.Code == statCode {
 			return true
 		}
 	}
@@ -51,7 +79,621 @@ func (c StatisticsConfig) IsValidStat(statCode string) bool {
 	return false
 }
 
+// AllianceRule defines how many teams a match has and how many players each team holds
+type AllianceRule struct {
+	MinNumber       int `json:"min_number" description:"Minimum number of teams in a match"`
+	MaxNumber       int `json:"max_number" description:"Maximum number of teams in a match"`
+	PlayerMinNumber int `json:"player_min_number" description:"Minimum number of players per team"`
+	PlayerMaxNumber int `json:"player_max_number" description:"Maximum number of players per team"`
+}
+
+// IsConfigured reports whether the alliance rule describes at least one team of at least one player
+func (a AllianceRule) IsConfigured() bool {
+	return a.MaxNumber > 0 && a.PlayerMaxNumber > 0
+}
+
+// MaxPlayers returns the number of players of a full match
+func (a AllianceRule) MaxPlayers() int {
+	return a.MaxNumber * a.PlayerMaxNumber
+}
+
+func (a AllianceRule) validate() []error {
+	var problems []error
+
+	if !a.IsConfigured() {
+		return nil
+	}
+
+	if a.MinNumber < 1 || a.MinNumber > a.MaxNumber {
+		problems = append(problems, fmt.Errorf("alliance.min_number: must be between 1 and max_number (%d)", a.MaxNumber))
+	}
+
+	if a.PlayerMinNumber < 1 || a.PlayerMinNumber > a.PlayerMaxNumber {
+		problems = append(problems, fmt.Errorf("alliance.player_min_number: must be between 1 and player_max_number (%d)", a.PlayerMaxNumber))
+	}
+
+	return problems
+}
+
+// MatchingRule constrains the tickets that can be matched together on an attribute
+type MatchingRule struct {
+	// Attribute is the player attribute the rule applies to, a ticket uses the average of its players
+	Attribute string `json:"attribute" description:"Player attribute the rule applies to, tickets use the average of their players"`
+
+	// Criteria is the kind of constraint, only "distance" is supported
+	Criteria string `json:"criteria" description:"Constraint kind, only distance is supported" default:"distance"`
+
+	// Reference is the maximum distance between the values of any two tickets in a match
+	Reference float64 `json:"reference" description:"Maximum distance between the attribute values of any two tickets in a match"`
+}
+
+// CompatibilityRule requires, or prefers when soft, the tickets of a match to agree on a ticket attribute
+type CompatibilityRule struct {
+	// Attribute is the ticket attribute compared, tickets without it are compatible with any ticket
+	Attribute string `json:"attribute" description:"Ticket attribute compared, tickets without it are compatible with any ticket"`
+
+	// Criteria is "equal" (same value) or "intersect" (the lists of values share an element)
+	Criteria string `json:"criteria" description:"equal: every ticket has the same value, intersect: the value lists of every ticket share an element"`
+
+	// Soft makes the rule a preference, a ticket breaking it adds Penalty to the group spread instead
+	Soft bool `json:"soft" description:"Prefer instead of require, a ticket breaking the rule adds penalty to the group spread" default:"false"`
+
+	// Penalty is the enriched stat distance a ticket breaking a soft rule adds to the group spread
+	Penalty float64 `json:"penalty" description:"Enriched stat distance a ticket breaking a soft rule adds to the group spread" default:"0"`
+}
+
+// FlexingRule replaces the reference of the matching rule on the same attribute once a ticket waited Duration seconds
+type FlexingRule struct {
+	Duration  int64   `json:"duration" description:"Ticket age in seconds after which the rule applies"`
+	Attribute string  `json:"attribute" description:"Player attribute of the matching rule to widen"`
+	Criteria  string  `json:"criteria" description:"Constraint kind, only distance is supported" default:"distance"`
+	Reference float64 `json:"reference" description:"Maximum distance between the attribute values of any two tickets once the rule applies"`
+}
+
+// AllianceFlexingRule relaxes the alliance minimums once a ticket waited Duration seconds
+type AllianceFlexingRule struct {
+	Duration        int64 `json:"duration" description:"Ticket age in seconds after which the rule applies"`
+	MinNumber       int   `json:"min_number" description:"Minimum number of teams in a match once the rule applies"`
+	PlayerMinNumber int   `json:"player_min_number" description:"Minimum number of players per team once the rule applies"`
+}
+
+// TeamBalanceRule selects how the tickets of a match are split across teams
+type TeamBalanceRule struct {
+	// Strategy is "snake" (serpentine draft), "exhaustive" (best split, for small matches) or "none" (pack teams in order)
+	// Default: "snake"
+	Strategy string `json:"strategy" description:"Team assignment strategy: snake (serpentine draft), exhaustive (best split for small matches) or none" default:"snake"`
+
+	// Metric is the team value compared across teams, "average" or "sum" of the enriched stat
+	// Default: "average"
+	Metric string `json:"metric" description:"Team value to balance: average or sum of the enriched stat" default:"average"`
+
+	// ExhaustiveMaxTickets is the largest number of tickets the exhaustive strategy searches, larger matches use
+	// snake. It is at most 12.
+	// Default: 8
+	ExhaustiveMaxTickets int `json:"exhaustive_max_tickets" description:"Largest number of tickets searched exhaustively, at most 12, larger matches use snake" default:"8"`
+}
+
+// GetStrategy returns the balancing strategy, defaulting to "snake"
+func (t TeamBalanceRule) GetStrategy() string {
+	if t.Strategy == "" {
+		return balanceStrategySnake
+	}
+
+	return t.Strategy
+}
+
+// GetMetric returns the balanced team value, defaulting to "average"
+func (t TeamBalanceRule) GetMetric() string {
+	if t.Metric == "" {
+		return balanceMetricAverage
+	}
+
+	return t.Metric
+}
+
+// GetExhaustiveMaxTickets returns the exhaustive search limit, defaulting to 8
+func (t TeamBalanceRule) GetExhaustiveMaxTickets() int {
+	if t.ExhaustiveMaxTickets <= 0 {
+		return 8
+	}
+
+	return t.ExhaustiveMaxTickets
+}
+
+func (t TeamBalanceRule) validate() []error {
+	var problems []error
+
+	switch t.GetStrategy() {
+	case balanceStrategySnake, balanceStrategyExhaustive, balanceStrategyNone:
+	default:
+		problems = append(problems, fmt.Errorf("team_balance.strategy: unsupported strategy %q", t.Strategy))
+	}
+
+	switch t.GetMetric() {
+	case balanceMetricAverage, balanceMetricSum:
+	default:
+		problems = append(problems, fmt.Errorf("team_balance.metric: unsupported metric %q", t.Metric))
+	}
+
+	if t.ExhaustiveMaxTickets > maxExhaustiveTickets {
+		problems = append(problems, fmt.Errorf("team_balance.exhaustive_max_tickets: %d is more than %d", t.ExhaustiveMaxTickets, maxExhaustiveTickets))
+	}
+
+	return problems
+}
+
+// RoleRule defines the roles every team of a match must be composed of
+type RoleRule struct {
+	// Attribute is the ticket attribute mapping each player ID to the roles the player accepts
+	// Default: "roles"
+	Attribute string `json:"attribute" description:"Ticket attribute mapping each player ID to the list of roles the player accepts" default:"roles"`
+
+	// Composition is the number of players of each role on a team, e.g. {"tank": 1, "healer": 1, "dps": 2}
+	Composition map[string]int `json:"composition" description:"Number of players of each role on a team, role queueing is disabled when empty"`
+}
+
+// GetAttribute returns the ticket attribute holding the player roles, defaulting to "roles"
+func (r RoleRule) GetAttribute() string {
+	if r.Attribute == "" {
+		return "roles"
+	}
+
+	return r.Attribute
+}
+
+// IsConfigured reports whether teams must follow a role composition
+func (r RoleRule) IsConfigured() bool {
+	return len(r.Composition) > 0
+}
+
+// TeamSize returns the number of players of a team following the composition
+func (r RoleRule) TeamSize() int {
+	size := 0
+	for _, count := range r.Composition {
+		size += count
+	}
+
+	return size
+}
+
+// Roles returns the roles of the composition in name order
+func (r RoleRule) Roles() []string {
+	roles := make([]string, 0, len(r.Composition))
+	for role := range r.Composition {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	return roles
+}
+
+func (r RoleRule) validate(alliance AllianceRule) []error {
+	if !r.IsConfigured() {
+		return nil
+	}
+
+	var problems []error
+
+	for _, role := range r.Roles() {
+		if role == "" {
+			problems = append(problems, fmt.Errorf("roles.composition: role names must not be empty"))
+		}
+		if r.Composition[role] < 1 {
+			problems = append(problems, fmt.Errorf("roles.composition.%s: must be at least 1", role))
+		}
+	}
+
+	if alliance.IsConfigured() && r.TeamSize() != alliance.PlayerMaxNumber {
+		problems = append(problems, fmt.Errorf("roles.composition: team size %d must equal alliance.player_max_number (%d)", r.TeamSize(), alliance.PlayerMaxNumber))
+	}
+
+	return problems
+}
+
+// CharacterRule constrains the characters, the stats selected by the players, of a match
+type CharacterRule struct {
+	// MirrorMatch is "allow", "forbid" or "penalize" the same character on opposing teams
+	// Default: "allow"
+	MirrorMatch string `json:"mirror_match" description:"Same character on opposing teams: allow, forbid or penalize" default:"allow"`
+
+	// MirrorPenalty is added to the spread of a group for every player whose character is already in the group,
+	// teammates included, when MirrorMatch is "penalize". Groups are formed before the team split.
+	MirrorPenalty float64 `json:"mirror_penalty" description:"Enriched stat distance added to a group for every player whose character is already in the group when mirror_match is penalize" default:"0"`
+
+	// UniquePerTeam forbids two players of a team from playing the same character
+	UniquePerTeam bool `json:"unique_per_team" description:"Forbid two players of a team from playing the same character" default:"false"`
+}
+
+// GetMirrorMatch returns the mirror match policy, defaulting to "allow"
+func (c CharacterRule) GetMirrorMatch() string {
+	if c.MirrorMatch == "" {
+		return mirrorMatchAllow
+	}
+
+	return c.MirrorMatch
+}
+
+func (c CharacterRule) validate() []error {
+	var problems []error
+
+	switch c.GetMirrorMatch() {
+	case mirrorMatchAllow, mirrorMatchForbid:
+	case mirrorMatchPenalize:
+		if c.MirrorPenalty == 0 {
+			problems = append(problems, fmt.Errorf("character_rule.mirror_penalty: must be positive when mirror_match is penalize"))
+		}
+	default:
+		problems = append(problems, fmt.Errorf("character_rule.mirror_match: unsupported policy %q", c.MirrorMatch))
+	}
+
+	if c.MirrorPenalty < 0 {
+		problems = append(problems, fmt.Errorf("character_rule.mirror_penalty: must not be negative"))
+	}
+
+	return problems
+}
+
+// QualityRule holds the reference values of the match quality score components. A component scores 1 when
+// perfect and 0.5 at its reference value, the score is the average of the components.
+type QualityRule struct {
+	// SpreadReference is the ticket value spread scoring 0.5
+	// Default: 100
+	SpreadReference float64 `json:"spread_reference" description:"Enriched stat spread between the tickets of a match scoring 0.5" default:"100"`
+
+	// ImbalanceReference is the team value difference scoring 0.5
+	// Default: 50
+	ImbalanceReference float64 `json:"imbalance_reference" description:"Team value difference scoring 0.5" default:"50"`
+
+	// LatencyReferenceMs is the worst player latency scoring 0.5
+	// Default: 100
+	LatencyReferenceMs float64 `json:"latency_reference_ms" description:"Worst player latency in ms to the preferred region scoring 0.5" default:"100"`
+
+	// WaitReferenceSeconds is the longest ticket wait scoring 0.5
+	// Default: 60
+	WaitReferenceSeconds float64 `json:"wait_reference_seconds" description:"Longest ticket wait in seconds scoring 0.5" default:"60"`
+}
+
+// GetSpreadReference returns the spread reference, defaulting to 100
+func (q QualityRule) GetSpreadReference() float64 {
+	if q.SpreadReference <= 0 {
+		return 100
+	}
+
+	return q.SpreadReference
+}
+
+// GetImbalanceReference returns the team imbalance reference, defaulting to 50
+func (q QualityRule) GetImbalanceReference() float64 {
+	if q.ImbalanceReference <= 0 {
+		return 50
+	}
+
+	return q.ImbalanceReference
+}
+
+// GetLatencyReferenceMs returns the latency reference, defaulting to 100
+func (q QualityRule) GetLatencyReferenceMs() float64 {
+	if q.LatencyReferenceMs <= 0 {
+		return 100
+	}
+
+	return q.LatencyReferenceMs
+}
+
+// GetWaitReferenceSeconds returns the wait reference, defaulting to 60
+func (q QualityRule) GetWaitReferenceSeconds() float64 {
+	if q.WaitReferenceSeconds <= 0 {
+		return 60
+	}
+
+	return q.WaitReferenceSeconds
+}
+
+func (q QualityRule) validate() []error {
+	var problems []error
+
+	if q.SpreadReference < 0 {
+		problems = append(problems, fmt.Errorf("quality.spread_reference: must not be negative"))
+	}
+	if q.ImbalanceReference < 0 {
+		problems = append(problems, fmt.Errorf("quality.imbalance_reference: must not be negative"))
+	}
+	if q.LatencyReferenceMs < 0 {
+		problems = append(problems, fmt.Errorf("quality.latency_reference_ms: must not be negative"))
+	}
+	if q.WaitReferenceSeconds < 0 {
+		problems = append(problems, fmt.Errorf("quality.wait_reference_seconds: must not be negative"))
+	}
+
+	return problems
+}
+
+// ServerPool is a DS Armada deployment or a list of AMS claim keys
+type ServerPool struct {
+	Deployment string   `json:"deployment" description:"DS Armada deployment"`
+	ClaimKeys  []string `json:"claim_keys" description:"AMS claim keys"`
+}
+
+// ServerSelectionRule decides where the sessions of generated matches are hosted
+type ServerSelectionRule struct {
+	// Provider is "AMS" to claim servers with claim keys, empty for DS Armada deployments
+	Provider string `json:"provider" description:"Server provider: AMS, or empty for DS Armada"`
+
+	// Deployment is the default DS Armada deployment
+	Deployment string `json:"deployment" description:"Default DS Armada deployment"`
+
+	// ClaimKeys are the default AMS claim keys
+	ClaimKeys []string `json:"claim_keys" description:"Default AMS claim keys"`
+
+	// Regions overrides the defaults for matches preferring a region
+	Regions map[string]ServerPool `json:"regions" description:"Server pool overrides keyed by the preferred region of a match"`
+
+	// ClientVersions overrides the defaults and the region overrides for matches of a client version
+	ClientVersions map[string]ServerPool `json:"client_versions" description:"Server pool overrides keyed by client version, applied after the region overrides"`
+
+	// ServerNameAttribute is the ticket attribute holding the local DS name, only tickets agreeing on it are grouped
+	ServerNameAttribute string `json:"server_name_attribute" description:"Ticket attribute holding the local DS name to direct the match to, tickets are only grouped with the same value"`
+
+	// ClientVersionAttribute is the ticket attribute holding the game version, only tickets agreeing on it are grouped
+	ClientVersionAttribute string `json:"client_version_attribute" description:"Ticket attribute holding the game version to pin the DS to, tickets are only grouped with the same value"`
+}
+
+func (s ServerSelectionRule) validate() []error {
+	if s.Provider != "" && s.Provider != serverProviderAMS {
+		return []error{fmt.Errorf("server_selection.provider: unsupported provider %q, expected %q or empty", s.Provider, serverProviderAMS)}
+	}
+
+	return nil
+}
+
+// BackfillRule decides which tickets may join the partial match of a backfill ticket
+type BackfillRule struct {
+	// Distance is the highest distance between the value of a joining ticket and the average of the seated
+	// players, 0 uses the matching distance flexed by the age of the backfill ticket
+	Distance float64 `json:"distance" description:"Highest enriched stat distance between a joining ticket and the average of the seated players, 0 uses the flexed matching distance" default:"0"`
+
+	// MovePlayers lets proposals move seated tickets, parties kept whole, to another team to lower the imbalance
+	MovePlayers bool `json:"move_players" description:"Let backfill proposals move seated parties between teams when it lowers the team imbalance" default:"false"`
+}
+
+func (b BackfillRule) validate() []error {
+	if b.Distance < 0 {
+		return []error{fmt.Errorf("backfill.distance: must not be negative")}
+	}
+
+	return nil
+}
+
 // GameRules defines the matchmaking rules parsed from JSON
 type GameRules struct {
-	Statistics StatisticsConfig `json:"statistics_config"`
+	// Version is the rules schema version, rulesets without it are treated as version 1
+	Version int `json:"version" description:"Rules schema version, rulesets without it are treated as version 1"`
+
+	// Extends names a base ruleset this ruleset is deep-merged over, it is empty once parsed
+	Extends string `json:"extends,omitempty" description:"Name of a base ruleset (a JSON file in RULES_BASE_DIR) to inherit fields from"`
+
+	// Strategy names the registered MatchingStrategy making the matches, see RegisterStrategy
+	// Default: "mmr_window"
+	Strategy string `json:"strategy" description:"Name of the matching strategy: mmr_window, role_queue or optimal_pairing" default:"mmr_window"`
+
+	// StrategyOptions configures the strategy, its fields depend on the strategy
+	StrategyOptions json.RawMessage `json:"strategy_options,omitempty" description:"Options of the matching strategy, their fields depend on the strategy"`
+
+	// strategy is the strategy built from Strategy and StrategyOptions by RulesFromJSON
+	strategy MatchingStrategy
+
+	Statistics StatisticsConfig `json:"statistics_config" description:"Statistic-based matchmaking configuration"`
+
+	// Alliance enables MakeMatches when configured, otherwise matching is delegated to AGS
+	Alliance AllianceRule `json:"alliance" description:"Team count and team size of a match, MakeMatches is delegated to AGS when not set"`
+
+	MatchingRules []MatchingRule `json:"matching_rule" description:"Constraints between the tickets of a match"`
+
+	// FlexingRules widen matching rules as tickets wait, e.g. 100 at 0s, 250 at 30s and 600 at 90s
+	FlexingRules []FlexingRule `json:"flexing_rule" description:"Steps widening matching rules as tickets wait"`
+
+	CompatibilityRules []CompatibilityRule `json:"compatibility_rule" description:"Ticket attributes the tickets of a match must, or should, agree on"`
+
+	AllianceFlexingRules []AllianceFlexingRule `json:"alliance_flexing_rule" description:"Steps relaxing the alliance minimums as tickets wait"`
+
+	TeamBalance TeamBalanceRule `json:"team_balance" description:"How the tickets of a match are split across teams"`
+
+	Roles RoleRule `json:"roles" description:"Role composition of every team, players declare their roles in a ticket attribute"`
+
+	// Characters constrains the stats selected by the players, which stand for their characters
+	Characters CharacterRule `json:"character_rule" description:"Mirror match and same-character constraints on the stats selected by the players"`
+
+	// RegionLatencyMaxMs is the highest latency a player may have to the match region, 0 disables the check
+	RegionLatencyMaxMs int64 `json:"region_latency_max_ms" description:"Highest latency in ms a player may have to the match region, 0 disables the check" default:"0"`
+
+	// RematchWindow keeps players who played together within this many seconds out of the same match, 0 disables it
+	RematchWindow int64 `json:"rematch_window" description:"Seconds during which players who played together are not matched together again, 0 disables the check" default:"0"`
+
+	ServerSelection ServerSelectionRule `json:"server_selection" description:"Server pool, local DS name and client version of the generated matches"`
+
+	Quality QualityRule `json:"quality" description:"Reference values of the match quality score written to the match attributes"`
+
+	// AutoBackfill marks matches that are not full as needing backfill
+	AutoBackfill bool `json:"auto_backfill" description:"Mark matches that are not full for backfill" default:"false"`
+
+	Backfill BackfillRule `json:"backfill" description:"Tickets proposed to fill the open seats of backfill tickets"`
+}
+
+// applyDefaults sets the parsed fields whose schema default differs from their zero value
+func (g *GameRules) applyDefaults() {
+	for i := range g.MatchingRules {
+		if g.MatchingRules[i].Criteria == "" {
+			g.MatchingRules[i].Criteria = "distance"
+		}
+	}
+
+	for i := range g.FlexingRules {
+		if g.FlexingRules[i].Criteria == "" {
+			g.FlexingRules[i].Criteria = "distance"
+		}
+	}
+}
+
+// GetStrategy returns the name of the matching strategy, defaulting to "mmr_window"
+func (g GameRules) GetStrategy() string {
+	if g.Strategy == "" {
+		return strategyMMRWindow
+	}
+
+	return g.Strategy
+}
+
+// MatchingStrategy returns the strategy selected by the rules, built from its options unless RulesFromJSON
+// already did
+func (g GameRules) MatchingStrategy() (MatchingStrategy, error) {
+	if g.strategy != nil {
+		return g.strategy, nil
+	}
+
+	return newStrategy(g.GetStrategy(), g.StrategyOptions)
+}
+
+// MatchingDistance returns the maximum distance between the enriched values of tickets in a match.
+// ok is false when no distance rule applies to the enriched key.
+func (g GameRules) MatchingDistance() (distance float64, ok bool) {
+	for _, rule := range g.MatchingRules {
+		if rule.Criteria == "distance" && rule.Attribute == g.Statistics.GetEnrichedKey() {
+			return rule.Reference, true
+		}
+	}
+
+	return 0, false
+}
+
+// MatchingDistanceAt returns the matching distance for a ticket that waited the given time, applying the
+// flexing rule with the longest duration already reached. ok is false when no distance applies.
+func (g GameRules) MatchingDistanceAt(waited time.Duration) (distance float64, ok bool) {
+	distance, ok = g.MatchingDistance()
+
+	reached := int64(-1)
+	for _, rule := range g.FlexingRules {
+		if rule.Criteria != "distance" || rule.Attribute != g.Statistics.GetEnrichedKey() {
+			continue
+		}
+
+		if rule.Duration > reached && time.Duration(rule.Duration)*time.Second <= waited {
+			distance, ok, reached = rule.Reference, true, rule.Duration
+		}
+	}
+
+	return distance, ok
+}
+
+// AllianceAt returns the alliance rule for a ticket that waited the given time, with the minimums of the
+// alliance flexing rule with the longest duration already reached
+func (g GameRules) AllianceAt(waited time.Duration) AllianceRule {
+	alliance := g.Alliance
+
+	reached := int64(-1)
+	for _, rule := range g.AllianceFlexingRules {
+		if rule.Duration > reached && time.Duration(rule.Duration)*time.Second <= waited {
+			alliance.MinNumber, alliance.PlayerMinNumber, reached = rule.MinNumber, rule.PlayerMinNumber, rule.Duration
+		}
+	}
+
+	return alliance
+}
+
+// Validate returns every problem found in the rules, or nil when they are usable
+func (g GameRules) Validate() []error {
+	var problems []error
+
+	seen := make(map[string]bool, len(g.Statistics.Statistics))
+	for i, stat := range g.Statistics.Statistics {
+		switch {
+		case stat.Code == "":
+			problems = append(problems, fmt.Errorf("statistics_config.statistics[%d].code: must not be empty", i))
+		case seen[stat.Code]:
+			problems = append(problems, fmt.Errorf("statistics_config.statistics[%d].code: duplicate stat code %q", i, stat.Code))
+		}
+		seen[stat.Code] = true
+	}
+
+	// EnrichTicket removes the configured stats after setting the enriched key
+	if g.Statistics.IsValidStat(g.Statistics.GetEnrichedKey()) {
+		problems = append(problems, fmt.Errorf("statistics_config.enriched_key: %q must not be one of the configured stat codes", g.Statistics.GetEnrichedKey()))
+	}
+
+	// EnrichTicket keeps the selected stat of every player under the selected stat key, after setting the enriched
+	// key and before removing the configured stats
+	if g.Statistics.GetSelectedStatKey() == g.Statistics.GetEnrichedKey() {
+		problems = append(problems, fmt.Errorf("statistics_config.selected_stat_key: must differ from enriched_key"))
+	}
+	if g.Statistics.IsValidStat(g.Statistics.GetSelectedStatKey()) {
+		problems = append(problems, fmt.Errorf("statistics_config.selected_stat_key: %q must not be one of the configured stat codes", g.Statistics.GetSelectedStatKey()))
+	}
+
+	problems = append(problems, g.Alliance.validate()...)
+	problems = append(problems, g.TeamBalance.validate()...)
+	problems = append(problems, g.Roles.validate(g.Alliance)...)
+	problems = append(problems, g.Characters.validate()...)
+	problems = append(problems, g.Quality.validate()...)
+	problems = append(problems, g.ServerSelection.validate()...)
+	problems = append(problems, g.Backfill.validate()...)
+
+	if strategy, err := g.MatchingStrategy(); err != nil {
+		problems = append(problems, err)
+	} else {
+		problems = append(problems, strategy.Validate(g)...)
+	}
+
+	if g.RegionLatencyMaxMs < 0 {
+		problems = append(problems, fmt.Errorf("region_latency_max_ms: must not be negative"))
+	}
+
+	if g.RematchWindow < 0 {
+		problems = append(problems, fmt.Errorf("rematch_window: must not be negative"))
+	}
+
+	for i, rule := range g.MatchingRules {
+		if rule.Criteria != "distance" {
+			problems = append(problems, fmt.Errorf("matching_rule[%d].criteria: unsupported criteria %q", i, rule.Criteria))
+		}
+		if rule.Reference < 0 {
+			problems = append(problems, fmt.Errorf("matching_rule[%d].reference: must not be negative", i))
+		}
+	}
+
+	for i, rule := range g.CompatibilityRules {
+		if rule.Attribute == "" {
+			problems = append(problems, fmt.Errorf("compatibility_rule[%d].attribute: must not be empty", i))
+		}
+		if rule.Criteria != compatibilityEqual && rule.Criteria != compatibilityIntersect {
+			problems = append(problems, fmt.Errorf("compatibility_rule[%d].criteria: unsupported criteria %q", i, rule.Criteria))
+		}
+		if rule.Penalty < 0 || rule.Soft && rule.Penalty == 0 {
+			problems = append(problems, fmt.Errorf("compatibility_rule[%d].penalty: must be positive for soft rules", i))
+		}
+	}
+
+	for i, rule := range g.FlexingRules {
+		if rule.Duration < 0 {
+			problems = append(problems, fmt.Errorf("flexing_rule[%d].duration: must not be negative", i))
+		}
+		if rule.Criteria != "distance" {
+			problems = append(problems, fmt.Errorf("flexing_rule[%d].criteria: unsupported criteria %q", i, rule.Criteria))
+		}
+		if rule.Reference < 0 {
+			problems = append(problems, fmt.Errorf("flexing_rule[%d].reference: must not be negative", i))
+		}
+	}
+
+	for i, rule := range g.AllianceFlexingRules {
+		if rule.Duration < 0 {
+			problems = append(problems, fmt.Errorf("alliance_flexing_rule[%d].duration: must not be negative", i))
+		}
+		if rule.MinNumber < 1 || rule.MinNumber > g.Alliance.MaxNumber {
+			problems = append(problems, fmt.Errorf("alliance_flexing_rule[%d].min_number: must be between 1 and alliance.max_number (%d)", i, g.Alliance.MaxNumber))
+		}
+		if rule.PlayerMinNumber < 1 || rule.PlayerMinNumber > g.Alliance.PlayerMaxNumber {
+			problems = append(problems, fmt.Errorf("alliance_flexing_rule[%d].player_min_number: must be between 1 and alliance.player_max_number (%d)", i, g.Alliance.PlayerMaxNumber))
+		}
+	}
+
+	return problems
 }
@@ -9,8 +9,17 @@ import (
 	"matchmaking-function-grpc-plugin-server-go/pkg/matchmaker"
 )
 
-// MatchMaker implements the MatchLogic interface for character-specific MMR matchmaking
-type MatchMaker struct{}
+// MatchMaker implements the MatchLogic[GameRules] interface for character-specific MMR matchmaking
+type MatchMaker struct {
+	// BaseRulesets resolves the "extends" field of rulesets, nil disables inheritance
+	BaseRulesets BaseRulesets
+
+	// History remembers recent match rosters to avoid rematches, it is shared by copies and nil disables it
+	History *MatchHistory
+
+	// sourcesVersion reuses the version of BaseRulesets for a few seconds, nil computes it on every call
+	sourcesVersion *cachedVersion
+}
 
 /*
 MatchLogic is a thing that has logic to take Tickets and make Matches. It also can decode match rules from json
@@ -23,16 +32,38 @@ MakeMatches returns a channel to which it will post matches as they are found, a
 all matches are exhausted.  It should also watch for cancellation on the provided scope.Ctx, at which point it should
 stop looking for matches and close the result channel.
 
+R is the type of the decoded ruleset returned by RulesFromJSON, so a mismatch between the rules a MatchLogic
+decodes and the rules it consumes is caught at compile time.
+
 ValidateTicket should return false AND api.ErrInvalidRequest when a ticket is not allowed to be queued
 */
-type MatchLogic interface {
+type MatchLogic[R any] interface {
 	// "TODO: add in scope"
-	BackfillMatches(scope *common.Scope, ticketProvider TicketProvider, matchRules interface{}) <-chan matchmaker.BackfillProposal
-	MakeMatches(scope *common.Scope, ticketProvider TicketProvider, matchRules interface{}) <-chan matchmaker.Match
-	RulesFromJSON(scope *common.Scope, json string) (interface{}, error)
-	GetStatCodes(scope *common.Scope, matchRules interface{}) []string
-	ValidateTicket(scope *common.Scope, matchTicket matchmaker.Ticket, matchRules interface{}) (bool, error)
-	EnrichTicket(scope *common.Scope, matchTicket matchmaker.Ticket, ruleSet interface{}) (ticket matchmaker.Ticket, err error)
+	BackfillMatches(scope *common.Scope, ticketProvider TicketProvider, matchRules R) <-chan matchmaker.BackfillProposal
+	MakeMatches(scope *common.Scope, ticketProvider TicketProvider, matchRules R) <-chan matchmaker.Match
+	RulesFromJSON(scope *common.Scope, json string) (R, error)
+	GetStatCodes(scope *common.Scope, matchRules R) []string
+	ValidateTicket(scope *common.Scope, matchTicket matchmaker.Ticket, matchRules R) (bool, error)
+	EnrichTicket(scope *common.Scope, matchTicket matchmaker.Ticket, ruleSet R) (ticket matchmaker.Ticket, err error)
+}
+
+// UntypedMatchLogic is the MatchLogic shape used before rules were typed, with rules passed as interface{}.
+// Existing implementations satisfy it unchanged and are served with UntypedMatchFunctionServer.
+type UntypedMatchLogic = MatchLogic[interface{}]
+
+// UntypedMatchFunctionServer serves an UntypedMatchLogic. It keeps the struct literals written before rules
+// were typed compiling once renamed, e.g. &server.UntypedMatchFunctionServer{MM: logic}.
+type UntypedMatchFunctionServer = MatchFunctionServer[interface{}]
+
+// NewUntypedMatchFunctionServer returns a server for a match logic written against interface{} rules
+func NewUntypedMatchFunctionServer(logic UntypedMatchLogic) *UntypedMatchFunctionServer {
+	return &UntypedMatchFunctionServer{MM: logic}
+}
+
+// RulesSourcesVersioner is implemented by match logics whose parsed rules depend on data outside the rules JSON.
+// RulesSourcesVersion must change whenever that data does, MatchFunctionServer adds it to the RulesCache key.
+type RulesSourcesVersioner interface {
+	RulesSourcesVersion() string
 }
 
 // TicketProvider provides a mechanism for a match function to get tickets from the match pool it's trying to make matches for
@@ -6,6 +6,9 @@ package server
 
 import (
 	"context"
+	"errors"
+	"io"
+	"time"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -15,10 +18,20 @@ import (
 	matchfunctiongrpc "matchmaking-function-grpc-plugin-server-go/pkg/pb"
 )
 
-// MatchFunctionServer is for the handler (upper level of match logic)
-type MatchFunctionServer struct {
+// MatchFunctionServer is for the handler (upper level of match logic), R is the rules type of its MatchLogic
+type MatchFunctionServer[R any] struct {
 	matchfunctiongrpc.UnimplementedMatchFunctionServer
-	MM MatchLogic
+	MM MatchLogic[R]
+
+	// RulesCache holds parsed rules across RPCs, nil disables caching
+	RulesCache *RulesCache[R]
+
+	// TickCache replays the matches of a retried MakeMatches tick, nil disables it
+	TickCache *TickCache
+
+	// TickBudget bounds the time an RPC spends on a tick, the results found when it runs out are streamed.
+	// 0 disables the budget.
+	TickBudget time.Duration
 }
 
 // matchTicketProvider contains the go channel of matchmaker tickets needed for making matches
@@ -37,12 +50,25 @@ func (m matchTicketProvider) GetBackfillTickets() chan matchmaker.BackfillTicket
 	return m.channelBackfillTickets
 }
 
+// rulesFromJSON returns the parsed rules for jsonRules, going through the RulesCache when one is configured.
+// Cached rules are parsed again once the sources of a RulesSourcesVersioner MatchLogic change.
+func (m *MatchFunctionServer[R]) rulesFromJSON(scope *common.Scope, jsonRules string) (R, error) {
+	sources := ""
+	if versioner, ok := m.MM.(RulesSourcesVersioner); ok {
+		sources = versioner.RulesSourcesVersion()
+	}
+
+	return m.RulesCache.GetOrParseWithSources(jsonRules, sources, func(jsonRules string) (R, error) {
+		return m.MM.RulesFromJSON(scope, jsonRules)
+	})
+}
+
 // GetStatCodes uses the assigned MatchMaker to get the stat codes of the ruleset
-func (m *MatchFunctionServer) GetStatCodes(ctx context.Context, req *matchfunctiongrpc.GetStatCodesRequest) (*matchfunctiongrpc.StatCodesResponse, error) {
+func (m *MatchFunctionServer[R]) GetStatCodes(ctx context.Context, req *matchfunctiongrpc.GetStatCodesRequest) (*matchfunctiongrpc.StatCodesResponse, error) {
 	scope := common.ChildScopeFromRemoteScope(ctx, "MatchFunctionServer.GetStatCodes")
 	defer scope.Finish()
 
-	rules, err := m.MM.RulesFromJSON(scope, req.Rules.Json)
+	rules, err := m.rulesFromJSON(scope, req.Rules.Json)
 	if err != nil {
 		scope.Log.Error("could not get rules from json", "error", err)
 
@@ -55,13 +81,13 @@ func (m *MatchFunctionServer) GetStatCodes(ctx context.Context, req *matchfuncti
 }
 
 // ValidateTicket uses the assigned MatchMaker to validate the ticket
-func (m *MatchFunctionServer) ValidateTicket(ctx context.Context, req *matchfunctiongrpc.ValidateTicketRequest) (*matchfunctiongrpc.ValidateTicketResponse, error) {
+func (m *MatchFunctionServer[R]) ValidateTicket(ctx context.Context, req *matchfunctiongrpc.ValidateTicketRequest) (*matchfunctiongrpc.ValidateTicketResponse, error) {
 	scope := common.ChildScopeFromRemoteScope(ctx, "MatchFunctionServer.ValidateTicket")
 	defer scope.Finish()
 
 	scope.Log.Info("validating ticket")
 
-	rules, err := m.MM.RulesFromJSON(scope, req.Rules.Json)
+	rules, err := m.rulesFromJSON(scope, req.Rules.Json)
 	if err != nil {
 		scope.Log.Error("could not get rules from json", "error", err)
 
@@ -76,13 +102,13 @@ func (m *MatchFunctionServer) ValidateTicket(ctx context.Context, req *matchfunc
 }
 
 // EnrichTicket uses the assigned MatchMaker to enrich the ticket
-func (m *MatchFunctionServer) EnrichTicket(ctx context.Context, req *matchfunctiongrpc.EnrichTicketRequest) (*matchfunctiongrpc.EnrichTicketResponse, error) {
+func (m *MatchFunctionServer[R]) EnrichTicket(ctx context.Context, req *matchfunctiongrpc.EnrichTicketRequest) (*matchfunctiongrpc.EnrichTicketResponse, error) {
 	scope := common.ChildScopeFromRemoteScope(ctx, "MatchFunctionServer.EnrichTicket")
 	defer scope.Finish()
 
 	scope.Log.Info("enriching ticket")
 
-	rules, err := m.MM.RulesFromJSON(scope, req.Rules.Json)
+	rules, err := m.rulesFromJSON(scope, req.Rules.Json)
 	if err != nil {
 		scope.Log.Error("could not get rules from json", "error", err)
 
@@ -102,22 +128,362 @@ func (m *MatchFunctionServer) EnrichTicket(ctx context.Context, req *matchfuncti
 	return response, nil
 }
 
-// MakeMatches returns UNIMPLEMENTED to let AGS use default matching algorithm
-func (m *MatchFunctionServer) MakeMatches(server matchfunctiongrpc.MatchFunction_MakeMatchesServer) error {
-	scope := common.ChildScopeFromRemoteScope(context.Background(), "MatchFunctionServer.MakeMatches")
+// MakeMatches reads the parameters and the tickets of one tick from the stream, feeds the tickets to the
+// assigned MatchMaker and streams back every match it makes. It returns UNIMPLEMENTED when the MatchMaker
+// delegates matching to AGS.
+func (m *MatchFunctionServer[R]) MakeMatches(server matchfunctiongrpc.MatchFunction_MakeMatchesServer) error {
+	scope := common.ChildScopeFromRemoteScope(server.Context(), "MatchFunctionServer.MakeMatches")
 	defer scope.Finish()
 
-	scope.Log.Info("MakeMatches returning UNIMPLEMENTED - using AGS default matching")
+	cancel := m.tickContext(scope)
+	defer cancel()
+
+	parameters, err := receiveMakeMatchesParameters(server)
+	if err != nil {
+		scope.Log.Error("invalid make matches stream", "error", err)
+
+		return err
+	}
+
+	scope.Log = scope.Log.With("tickID", parameters.TickId, "abTraceID", parameters.GetScope().GetAbTraceId())
+
+	rules, err := m.rulesFromJSON(scope, parameters.GetRules().GetJson())
+	if err != nil {
+		scope.Log.Error("could not get rules from json", "error", err)
+
+		return err
+	}
+
+	tickets, matchPool, err := receiveMakeMatchesTickets(scope, server)
+	if err != nil {
+		scope.Log.Error("invalid make matches stream", "error", err)
+
+		return err
+	}
+
+	if cached, ok := m.TickCache.Get(matchPool, parameters.TickId); ok {
+		scope.Log.Info("replaying matches of a retried tick", "matchPool", matchPool, "matches", len(cached))
+		tickReplays.Inc()
+
+		for _, match := range cached {
+			if err = server.Send(&matchfunctiongrpc.MatchResponse{Match: match}); err != nil {
+				scope.Log.Error("could not send match", "error", err)
+
+				return err
+			}
+		}
+
+		return nil
+	}
 
-	return status.Error(codes.Unimplemented, "MakeMatches not implemented - using AGS default matching")
+	scope.Log.Info("making matches", "tickets", len(tickets))
+
+	ticketProvider := matchTicketProvider{channelTickets: ticketsChannel(tickets)}
+	matches := m.MM.MakeMatches(scope, ticketProvider, rules)
+	if matches == nil {
+		scope.Log.Info("MakeMatches returning UNIMPLEMENTED - using AGS default matching")
+
+		return status.Error(codes.Unimplemented, "MakeMatches not implemented - using AGS default matching")
+	}
+
+	// remember what was produced even when the stream breaks, a retry must not get a conflicting set.
+	// A tick that produced nothing has nothing to conflict with, its retry is matched again.
+	var produced []*matchfunctiongrpc.Match
+	defer func() {
+		if len(produced) > 0 {
+			m.TickCache.Put(matchPool, parameters.TickId, produced)
+		}
+	}()
+
+	sent := 0
+	for match := range matches {
+		protoMatch := matchfunctiongrpc.MatchfunctionMatchToProtoMatch(match)
+		produced = append(produced, protoMatch)
+
+		err = server.Send(&matchfunctiongrpc.MatchResponse{Match: protoMatch})
+		if err != nil {
+			scope.Log.Error("could not send match", "error", err)
+			cancel()
+			drain(matches)
+
+			return err
+		}
+		sent++
+	}
+
+	if errors.Is(scope.Ctx.Err(), context.DeadlineExceeded) {
+		scope.Log.Warn("tick budget exhausted, sent the matches found so far", "budget", m.TickBudget, "matches", sent)
+		tickBudgetExhausted.WithLabelValues("MakeMatches").Inc()
+	}
+
+	scope.Log.Info("matches sent", "matches", sent)
+
+	return nil
+}
+
+// tickContext replaces scope.Ctx by a child context cancelled when the tick budget runs out, if any.
+// The returned function cancels it and must be called once the tick is over.
+func (m *MatchFunctionServer[R]) tickContext(scope *common.Scope) context.CancelFunc {
+	var cancel context.CancelFunc
+	if m.TickBudget > 0 {
+		scope.Ctx, cancel = context.WithTimeout(scope.Ctx, m.TickBudget)
+	} else {
+		scope.Ctx, cancel = context.WithCancel(scope.Ctx)
+	}
+
+	return cancel
 }
 
-// BackfillMatches returns UNIMPLEMENTED to let AGS use default backfill logic
-func (m *MatchFunctionServer) BackfillMatches(server matchfunctiongrpc.MatchFunction_BackfillMatchesServer) error {
-	scope := common.ChildScopeFromRemoteScope(context.Background(), "MatchFunctionServer.BackfillMatches")
+// receiveMakeMatchesParameters reads the parameters message that must open a MakeMatches stream
+func receiveMakeMatchesParameters(server matchfunctiongrpc.MatchFunction_MakeMatchesServer) (*matchfunctiongrpc.MakeMatchesRequest_MakeMatchesParameters, error) {
+	req, err := server.Recv()
+	if errors.Is(err, io.EOF) {
+		return nil, status.Error(codes.InvalidArgument, "stream closed before the parameters message")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	parameters := req.GetParameters()
+	if parameters == nil {
+		return nil, status.Error(codes.InvalidArgument, "first message must be the parameters message")
+	}
+
+	if parameters.GetRules() == nil {
+		return nil, status.Error(codes.InvalidArgument, "parameters message has no rules")
+	}
+
+	return parameters, nil
+}
+
+// receiveMakeMatchesTickets reads tickets until the client half-closes the stream.
+// Every ticket must share the match pool of the first one, which is returned, tickets without players are discarded.
+func receiveMakeMatchesTickets(scope *common.Scope, server matchfunctiongrpc.MatchFunction_MakeMatchesServer) ([]matchmaker.Ticket, string, error) {
+	var tickets []matchmaker.Ticket
+	matchPool, poolSet := "", false
+
+	for {
+		req, err := server.Recv()
+		if errors.Is(err, io.EOF) {
+			return tickets, matchPool, nil
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		switch request := req.GetRequestType().(type) {
+		case *matchfunctiongrpc.MakeMatchesRequest_Parameters:
+			return nil, "", status.Error(codes.InvalidArgument, "parameters message must be sent exactly once")
+		case *matchfunctiongrpc.MakeMatchesRequest_Ticket:
+			ticket := request.Ticket
+			if ticket == nil {
+				return nil, "", status.Error(codes.InvalidArgument, "empty ticket message")
+			}
+
+			if !poolSet {
+				matchPool, poolSet = ticket.MatchPool, true
+			} else if ticket.MatchPool != matchPool {
+				return nil, "", status.Errorf(codes.InvalidArgument,
+					"ticket %s: match pool %q differs from the stream match pool %q", ticket.TicketId, ticket.MatchPool, matchPool)
+			}
+
+			if len(ticket.Players) == 0 {
+				scope.Log.Warn("discarding ticket without players", "ticketID", ticket.TicketId)
+
+				continue
+			}
+
+			tickets = append(tickets, matchfunctiongrpc.ProtoTicketToMatchfunctionTicket(ticket))
+		default:
+			return nil, "", status.Errorf(codes.InvalidArgument, "unexpected message type %T", request)
+		}
+	}
+}
+
+// ticketsChannel returns a closed channel holding tickets
+func ticketsChannel[T any](tickets []T) chan T {
+	channel := make(chan T, len(tickets))
+	for _, ticket := range tickets {
+		channel <- ticket
+	}
+	close(channel)
+
+	return channel
+}
+
+// drain discards the remaining values of a result channel in the background so its producer can exit
+func drain[T any](results <-chan T) {
+	go func() {
+		for range results {
+		}
+	}()
+}
+
+// BackfillMatches reads the parameters, the backfill tickets and then the tickets of one tick from the stream,
+// feeds both to the assigned MatchMaker and streams back every backfill proposal it makes. It returns
+// UNIMPLEMENTED when the MatchMaker delegates backfill to AGS.
+func (m *MatchFunctionServer[R]) BackfillMatches(server matchfunctiongrpc.MatchFunction_BackfillMatchesServer) error {
+	scope := common.ChildScopeFromRemoteScope(server.Context(), "MatchFunctionServer.BackfillMatches")
 	defer scope.Finish()
 
-	scope.Log.Info("BackfillMatches returning UNIMPLEMENTED - using AGS default backfill")
+	cancel := m.tickContext(scope)
+	defer cancel()
+
+	parameters, err := receiveBackfillParameters(server)
+	if err != nil {
+		scope.Log.Error("invalid backfill matches stream", "error", err)
+
+		return err
+	}
+
+	scope.Log = scope.Log.With("tickID", parameters.TickId, "abTraceID", parameters.GetScope().GetAbTraceId())
+
+	rules, err := m.rulesFromJSON(scope, parameters.GetRules().GetJson())
+	if err != nil {
+		scope.Log.Error("could not get rules from json", "error", err)
+
+		return err
+	}
+
+	backfillTickets, tickets, err := receiveBackfillTickets(scope, server)
+	if err != nil {
+		scope.Log.Error("invalid backfill matches stream", "error", err)
+
+		return err
+	}
+
+	scope.Log.Info("backfilling matches", "backfillTickets", len(backfillTickets), "tickets", len(tickets))
+
+	ticketProvider := matchTicketProvider{
+		channelTickets:         ticketsChannel(tickets),
+		channelBackfillTickets: ticketsChannel(backfillTickets),
+	}
+	proposals := m.MM.BackfillMatches(scope, ticketProvider, rules)
+	if proposals == nil {
+		scope.Log.Info("BackfillMatches returning UNIMPLEMENTED - using AGS default backfill")
+
+		return status.Error(codes.Unimplemented, "BackfillMatches not implemented - using AGS default backfill")
+	}
+
+	sent := 0
+	for proposal := range proposals {
+		protoProposal := matchfunctiongrpc.MatchfunctionBackfillProposalToProtoBackfillProposal(proposal)
+
+		err = server.Send(&matchfunctiongrpc.BackfillResponse{BackfillProposal: protoProposal})
+		if err != nil {
+			scope.Log.Error("could not send backfill proposal", "error", err)
+			cancel()
+			drain(proposals)
 
-	return status.Error(codes.Unimplemented, "BackfillMatches not implemented - using AGS default backfill")
+			return err
+		}
+		sent++
+	}
+
+	if errors.Is(scope.Ctx.Err(), context.DeadlineExceeded) {
+		scope.Log.Warn("tick budget exhausted, sent the backfill proposals found so far", "budget", m.TickBudget, "proposals", sent)
+		tickBudgetExhausted.WithLabelValues("BackfillMatches").Inc()
+	}
+
+	scope.Log.Info("backfill proposals sent", "proposals", sent)
+
+	return nil
+}
+
+// receiveBackfillParameters reads the parameters message that must open a BackfillMatches stream
+func receiveBackfillParameters(server matchfunctiongrpc.MatchFunction_BackfillMatchesServer) (*matchfunctiongrpc.BackfillMakeMatchesRequest_MakeMatchesParameters, error) {
+	req, err := server.Recv()
+	if errors.Is(err, io.EOF) {
+		return nil, status.Error(codes.InvalidArgument, "stream closed before the parameters message")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	parameters := req.GetParameters()
+	if parameters == nil {
+		return nil, status.Error(codes.InvalidArgument, "first message must be the parameters message")
+	}
+
+	if parameters.GetRules() == nil {
+		return nil, status.Error(codes.InvalidArgument, "parameters message has no rules")
+	}
+
+	return parameters, nil
+}
+
+// receiveBackfillTickets reads backfill tickets and then tickets until the client half-closes the stream.
+// A backfill ticket after a ticket is rejected, every ticket of both kinds must share the match pool of the first
+// one and tickets without players are discarded.
+func receiveBackfillTickets(scope *common.Scope, server matchfunctiongrpc.MatchFunction_BackfillMatchesServer) ([]matchmaker.BackfillTicket, []matchmaker.Ticket, error) {
+	var backfillTickets []matchmaker.BackfillTicket
+	var tickets []matchmaker.Ticket
+	matchPool, poolSet := "", false
+	ticketSeen := false // also set by the discarded tickets, the order applies to every ticket message
+
+	checkPool := func(ticketID, ticketPool string) error {
+		if !poolSet {
+			matchPool, poolSet = ticketPool, true
+		} else if ticketPool != matchPool {
+			return status.Errorf(codes.InvalidArgument,
+				"ticket %s: match pool %q differs from the stream match pool %q", ticketID, ticketPool, matchPool)
+		}
+
+		return nil
+	}
+
+	for {
+		req, err := server.Recv()
+		if errors.Is(err, io.EOF) {
+			return backfillTickets, tickets, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch request := req.GetRequestType().(type) {
+		case *matchfunctiongrpc.BackfillMakeMatchesRequest_Parameters:
+			return nil, nil, status.Error(codes.InvalidArgument, "parameters message must be sent exactly once")
+		case *matchfunctiongrpc.BackfillMakeMatchesRequest_BackfillTicket:
+			backfillTicket := request.BackfillTicket
+			if backfillTicket == nil {
+				return nil, nil, status.Error(codes.InvalidArgument, "empty backfill ticket message")
+			}
+
+			if ticketSeen {
+				return nil, nil, status.Errorf(codes.InvalidArgument,
+					"backfill ticket %s: backfill tickets must be sent before the tickets", backfillTicket.TicketId)
+			}
+
+			if err = checkPool(backfillTicket.TicketId, backfillTicket.MatchPool); err != nil {
+				return nil, nil, err
+			}
+
+			if backfillTicket.PartialMatch == nil {
+				return nil, nil, status.Errorf(codes.InvalidArgument, "backfill ticket %s: no partial match", backfillTicket.TicketId)
+			}
+
+			backfillTickets = append(backfillTickets, matchfunctiongrpc.ProtoBackfillTicketToMatchfunctionBackfillTicket(backfillTicket))
+		case *matchfunctiongrpc.BackfillMakeMatchesRequest_Ticket:
+			ticket := request.Ticket
+			if ticket == nil {
+				return nil, nil, status.Error(codes.InvalidArgument, "empty ticket message")
+			}
+			ticketSeen = true
+
+			if err = checkPool(ticket.TicketId, ticket.MatchPool); err != nil {
+				return nil, nil, err
+			}
+
+			if len(ticket.Players) == 0 {
+				scope.Log.Warn("discarding ticket without players", "ticketID", ticket.TicketId)
+
+				continue
+			}
+
+			tickets = append(tickets, matchfunctiongrpc.ProtoTicketToMatchfunctionTicket(ticket))
+		default:
+			return nil, nil, status.Errorf(codes.InvalidArgument, "unexpected message type %T", request)
+		}
+	}
 }
@@ -0,0 +1,358 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"matchmaking-function-grpc-plugin-server-go/pkg/common"
+	"matchmaking-function-grpc-plugin-server-go/pkg/matchmaker"
+	matchfunctiongrpc "matchmaking-function-grpc-plugin-server-go/pkg/pb"
+	"matchmaking-function-grpc-plugin-server-go/pkg/playerdata"
+)
+
+// fakeStream replays requests to a streaming RPC and records the responses it sends
+type fakeStream[Req, Res any] struct {
+	grpc.ServerStream
+	ctx      context.Context
+	requests []*Req
+	sent     []*Res
+}
+
+type fakeMakeMatchesStream = fakeStream[matchfunctiongrpc.MakeMatchesRequest, matchfunctiongrpc.MatchResponse]
+
+type fakeBackfillMatchesStream = fakeStream[matchfunctiongrpc.BackfillMakeMatchesRequest, matchfunctiongrpc.BackfillResponse]
+
+func (s *fakeStream[Req, Res]) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeStream[Req, Res]) Recv() (*Req, error) {
+	if len(s.requests) == 0 {
+		return nil, io.EOF
+	}
+
+	request := s.requests[0]
+	s.requests = s.requests[1:]
+
+	return request, nil
+}
+
+func (s *fakeStream[Req, Res]) Send(response *Res) error {
+	s.sent = append(s.sent, response)
+
+	return nil
+}
+
+// blockingMatchLogic posts one match of the first two tickets, then blocks until scope.Ctx is done
+type blockingMatchLogic struct{}
+
+func (blockingMatchLogic) MakeMatches(scope *common.Scope, ticketProvider TicketProvider, _ string) <-chan matchmaker.Match {
+	results := make(chan matchmaker.Match)
+
+	go func() {
+		defer close(results)
+
+		var tickets []matchmaker.Ticket
+		for ticket := range ticketProvider.GetTickets() {
+			tickets = append(tickets, ticket)
+		}
+
+		match := matchmaker.Match{Tickets: tickets[:2]}
+		for _, ticket := range match.Tickets {
+			match.Teams = append(match.Teams, matchmaker.Team{UserIDs: []playerdata.ID{ticket.Players[0].PlayerID}})
+		}
+
+		select {
+		case results <- match:
+		case <-scope.Ctx.Done():
+			return
+		}
+
+		<-scope.Ctx.Done()
+	}()
+
+	return results
+}
+
+func (blockingMatchLogic) BackfillMatches(*common.Scope, TicketProvider, string) <-chan matchmaker.BackfillProposal {
+	return nil
+}
+
+func (blockingMatchLogic) RulesFromJSON(_ *common.Scope, json string) (string, error) {
+	return json, nil
+}
+
+func (blockingMatchLogic) GetStatCodes(*common.Scope, string) []string {
+	return nil
+}
+
+func (blockingMatchLogic) ValidateTicket(*common.Scope, matchmaker.Ticket, string) (bool, error) {
+	return true, nil
+}
+
+func (blockingMatchLogic) EnrichTicket(_ *common.Scope, ticket matchmaker.Ticket, _ string) (matchmaker.Ticket, error) {
+	return ticket, nil
+}
+
+// countingMatchLogic posts matches matches of the first ticket on every MakeMatches call, each with the
+// number of the call in its match attributes
+type countingMatchLogic struct {
+	blockingMatchLogic
+	matches int
+	calls   int
+}
+
+func (l *countingMatchLogic) MakeMatches(_ *common.Scope, ticketProvider TicketProvider, _ string) <-chan matchmaker.Match {
+	l.calls++
+
+	var tickets []matchmaker.Ticket
+	for ticket := range ticketProvider.GetTickets() {
+		tickets = append(tickets, ticket)
+	}
+
+	results := make(chan matchmaker.Match, l.matches)
+	for range l.matches {
+		results <- matchmaker.Match{Tickets: tickets[:1], MatchAttributes: map[string]interface{}{"call": float64(l.calls)}}
+	}
+	close(results)
+
+	return results
+}
+
+// parametersRequest returns the parameters message of a MakeMatches tick
+func parametersRequest(tickID uint64) *matchfunctiongrpc.MakeMatchesRequest {
+	return &matchfunctiongrpc.MakeMatchesRequest{RequestType: &matchfunctiongrpc.MakeMatchesRequest_Parameters{
+		Parameters: &matchfunctiongrpc.MakeMatchesRequest_MakeMatchesParameters{Rules: &matchfunctiongrpc.Rules{Json: "{}"}, TickId: tickID},
+	}}
+}
+
+// ticketRequest returns the message of a one player ticket of matchPool
+func ticketRequest(id, matchPool string) *matchfunctiongrpc.MakeMatchesRequest {
+	return &matchfunctiongrpc.MakeMatchesRequest{RequestType: &matchfunctiongrpc.MakeMatchesRequest_Ticket{Ticket: &matchfunctiongrpc.Ticket{
+		TicketId:  "ticket-" + id,
+		MatchPool: matchPool,
+		Players:   []*matchfunctiongrpc.Ticket_PlayerData{{PlayerId: "player-" + id}},
+	}}}
+}
+
+// tickStream returns a MakeMatches stream of the parameters of tickID followed by a ticket of matchPool per id
+func tickStream(tickID uint64, matchPool string, ids ...string) *fakeMakeMatchesStream {
+	stream := &fakeMakeMatchesStream{ctx: context.Background(), requests: []*matchfunctiongrpc.MakeMatchesRequest{parametersRequest(tickID)}}
+	for _, id := range ids {
+		stream.requests = append(stream.requests, ticketRequest(id, matchPool))
+	}
+
+	return stream
+}
+
+func TestMakeMatchesStreamsPostedMatchesWhenTheTickBudgetRunsOut(t *testing.T) {
+	stream := tickStream(1, "pool", "a", "b", "c")
+
+	exhausted := tickBudgetExhausted.WithLabelValues("MakeMatches")
+	before := testutil.ToFloat64(exhausted)
+
+	server := &MatchFunctionServer[string]{MM: blockingMatchLogic{}, TickBudget: 50 * time.Millisecond}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.MakeMatches(stream)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("MakeMatches returned %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("MakeMatches did not return once the tick budget ran out")
+	}
+
+	if len(stream.sent) != 1 {
+		t.Fatalf("sent %d matches, want the 1 posted before the budget ran out", len(stream.sent))
+	}
+	if tickets := stream.sent[0].GetMatch().GetTickets(); len(tickets) != 2 {
+		t.Errorf("sent match has %d tickets, want 2", len(tickets))
+	}
+
+	if got := testutil.ToFloat64(exhausted) - before; got != 1 {
+		t.Errorf("tick_budget_exhausted_total{rpc=\"MakeMatches\"} increased by %v, want 1", got)
+	}
+}
+
+func TestMakeMatchesChecksTheStreamContract(t *testing.T) {
+	tests := []struct {
+		name     string
+		requests []*matchfunctiongrpc.MakeMatchesRequest
+		wantCode codes.Code
+	}{
+		{
+			name:     "parameters then tickets",
+			requests: tickStream(1, "pool", "a", "b").requests,
+			wantCode: codes.OK,
+		},
+		{
+			name:     "no parameters",
+			requests: []*matchfunctiongrpc.MakeMatchesRequest{ticketRequest("a", "pool"), parametersRequest(1)},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "empty stream",
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "parameters sent twice",
+			requests: []*matchfunctiongrpc.MakeMatchesRequest{parametersRequest(1), ticketRequest("a", "pool"), parametersRequest(1)},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "mixed match pools",
+			requests: []*matchfunctiongrpc.MakeMatchesRequest{parametersRequest(1), ticketRequest("a", "pool"), ticketRequest("b", "other")},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logic := &countingMatchLogic{matches: 1}
+			server := &MatchFunctionServer[string]{MM: logic}
+
+			err := server.MakeMatches(&fakeMakeMatchesStream{ctx: context.Background(), requests: tt.requests})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("MakeMatches returned %v, want code %v", err, tt.wantCode)
+			}
+
+			if tt.wantCode != codes.OK && logic.calls != 0 {
+				t.Error("the match logic ran on a rejected stream")
+			}
+		})
+	}
+}
+
+// backfillParametersRequest returns the parameters message of a BackfillMatches tick
+func backfillParametersRequest() *matchfunctiongrpc.BackfillMakeMatchesRequest {
+	return &matchfunctiongrpc.BackfillMakeMatchesRequest{RequestType: &matchfunctiongrpc.BackfillMakeMatchesRequest_Parameters{
+		Parameters: &matchfunctiongrpc.BackfillMakeMatchesRequest_MakeMatchesParameters{Rules: &matchfunctiongrpc.Rules{Json: "{}"}, TickId: 1},
+	}}
+}
+
+// backfillTicketRequest returns the message of a backfill ticket of matchPool with an empty partial match
+func backfillTicketRequest(id, matchPool string) *matchfunctiongrpc.BackfillMakeMatchesRequest {
+	return &matchfunctiongrpc.BackfillMakeMatchesRequest{RequestType: &matchfunctiongrpc.BackfillMakeMatchesRequest_BackfillTicket{
+		BackfillTicket: &matchfunctiongrpc.BackfillTicket{
+			TicketId:     "backfill-" + id,
+			MatchPool:    matchPool,
+			PartialMatch: &matchfunctiongrpc.BackfillTicket_PartialMatch{},
+		},
+	}}
+}
+
+// backfillStreamTicketRequest returns the message of a ticket of matchPool, with one player per ID, in a backfill stream
+func backfillStreamTicketRequest(id, matchPool string, playerIDs ...string) *matchfunctiongrpc.BackfillMakeMatchesRequest {
+	ticket := &matchfunctiongrpc.Ticket{TicketId: "ticket-" + id, MatchPool: matchPool}
+	for _, playerID := range playerIDs {
+		ticket.Players = append(ticket.Players, &matchfunctiongrpc.Ticket_PlayerData{PlayerId: playerID})
+	}
+
+	return &matchfunctiongrpc.BackfillMakeMatchesRequest{RequestType: &matchfunctiongrpc.BackfillMakeMatchesRequest_Ticket{Ticket: ticket}}
+}
+
+func TestReceiveBackfillTicketsReadsBackfillTicketsThenTickets(t *testing.T) {
+	stream := &fakeBackfillMatchesStream{ctx: context.Background(), requests: []*matchfunctiongrpc.BackfillMakeMatchesRequest{
+		backfillTicketRequest("a", "pool"),
+		backfillTicketRequest("b", "pool"),
+		backfillStreamTicketRequest("c", "pool", "player-c"),
+		backfillStreamTicketRequest("empty", "pool"),
+		backfillStreamTicketRequest("d", "pool", "player-d"),
+	}}
+
+	backfillTickets, tickets, err := receiveBackfillTickets(testScope(), stream)
+	if err != nil {
+		t.Fatalf("receiveBackfillTickets returned %v", err)
+	}
+
+	if len(backfillTickets) != 2 || backfillTickets[0].TicketID != "backfill-a" || backfillTickets[1].TicketID != "backfill-b" {
+		t.Errorf("backfill tickets = %v, want backfill-a and backfill-b", backfillTickets)
+	}
+
+	// the ticket without players is discarded
+	if len(tickets) != 2 || tickets[0].TicketID != "ticket-c" || tickets[1].TicketID != "ticket-d" {
+		t.Errorf("tickets = %v, want ticket-c and ticket-d", tickets)
+	}
+}
+
+func TestBackfillMatchesChecksTheStreamContract(t *testing.T) {
+	tests := []struct {
+		name     string
+		requests []*matchfunctiongrpc.BackfillMakeMatchesRequest
+		wantErr  string
+	}{
+		{
+			name:     "no parameters",
+			requests: []*matchfunctiongrpc.BackfillMakeMatchesRequest{backfillTicketRequest("a", "pool")},
+			wantErr:  "first message must be the parameters message",
+		},
+		{
+			name:    "empty stream",
+			wantErr: "stream closed before the parameters message",
+		},
+		{
+			name: "parameters sent twice",
+			requests: []*matchfunctiongrpc.BackfillMakeMatchesRequest{
+				backfillParametersRequest(), backfillTicketRequest("a", "pool"), backfillParametersRequest(),
+			},
+			wantErr: "parameters message must be sent exactly once",
+		},
+		{
+			name: "backfill ticket after a ticket",
+			requests: []*matchfunctiongrpc.BackfillMakeMatchesRequest{
+				backfillParametersRequest(), backfillStreamTicketRequest("a", "pool", "player-a"), backfillTicketRequest("b", "pool"),
+			},
+			wantErr: "backfill tickets must be sent before the tickets",
+		},
+		{
+			name: "backfill ticket after a discarded ticket",
+			requests: []*matchfunctiongrpc.BackfillMakeMatchesRequest{
+				backfillParametersRequest(), backfillStreamTicketRequest("a", "pool"), backfillTicketRequest("b", "pool"),
+			},
+			wantErr: "backfill tickets must be sent before the tickets",
+		},
+		{
+			name: "mixed match pools",
+			requests: []*matchfunctiongrpc.BackfillMakeMatchesRequest{
+				backfillParametersRequest(), backfillTicketRequest("a", "pool"), backfillStreamTicketRequest("b", "other", "player-b"),
+			},
+			wantErr: `match pool "other" differs from the stream match pool "pool"`,
+		},
+		{
+			name: "backfill ticket without a partial match",
+			requests: []*matchfunctiongrpc.BackfillMakeMatchesRequest{
+				backfillParametersRequest(),
+				{RequestType: &matchfunctiongrpc.BackfillMakeMatchesRequest_BackfillTicket{BackfillTicket: &matchfunctiongrpc.BackfillTicket{TicketId: "backfill-a", MatchPool: "pool"}}},
+			},
+			wantErr: "no partial match",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &MatchFunctionServer[string]{MM: blockingMatchLogic{}}
+
+			err := server.BackfillMatches(&fakeBackfillMatchesStream{ctx: context.Background(), requests: tt.requests})
+			if status.Code(err) != codes.InvalidArgument || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("BackfillMatches returned %v, want InvalidArgument containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// MatchHistory remembers which players recently played together and the rosters of the match sessions
+// backfilled by this replica, so MakeMatches can avoid rematches and the players of those sessions when a
+// ticket excludes them. Entries expire after the TTL and at most capacity players and capacity sessions are
+// kept, the least recently updated are evicted first.
+// It is safe for concurrent use, a nil MatchHistory remembers nothing.
+type MatchHistory struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	players  expiringLRU[map[string]time.Time] // co-players of each player with the time they last played together
+	sessions expiringLRU[[]string]             // player IDs of each match session
+}
+
+// expiringLRU is a bounded map whose entries expire a TTL after their last update, ordered by last update
+// with the most recently updated entry in front. It is not safe for concurrent use.
+type expiringLRU[V any] struct {
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type expiringEntry[V any] struct {
+	key     string
+	value   V
+	updated time.Time
+}
+
+// NewMatchHistory returns a MatchHistory keeping at most capacity players and sessions for ttl.
+// A capacity below 1 or a ttl of 0 returns nil, which disables the history.
+func NewMatchHistory(capacity int, ttl time.Duration) *MatchHistory {
+	if capacity < 1 || ttl <= 0 {
+		return nil
+	}
+
+	return &MatchHistory{
+		capacity: capacity,
+		ttl:      ttl,
+		players:  newExpiringLRU[map[string]time.Time](),
+		sessions: newExpiringLRU[[]string](),
+	}
+}
+
+// RecordMatch remembers that the players played together at the given time
+func (h *MatchHistory) RecordMatch(playerIDs []string, at time.Time) {
+	if h == nil || len(playerIDs) < 2 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, playerID := range playerIDs {
+		coPlayers, _ := h.players.get(playerID, at, h.ttl)
+		if coPlayers == nil {
+			coPlayers = make(map[string]time.Time, len(playerIDs)-1)
+		}
+
+		for id, played := range coPlayers {
+			if at.Sub(played) >= h.ttl {
+				delete(coPlayers, id)
+			}
+		}
+		for _, other := range playerIDs {
+			if other != playerID {
+				coPlayers[other] = at
+			}
+		}
+
+		h.players.put(playerID, coPlayers, at, h.capacity)
+	}
+}
+
+// RecentCoPlayers returns the players who played with playerID less than window before now
+func (h *MatchHistory) RecentCoPlayers(playerID string, window time.Duration, now time.Time) []string {
+	if h == nil || window <= 0 {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	coPlayers, ok := h.players.get(playerID, now, h.ttl)
+	if !ok {
+		return nil
+	}
+
+	var recent []string
+	for id, played := range coPlayers {
+		if now.Sub(played) < min(window, h.ttl) {
+			recent = append(recent, id)
+		}
+	}
+
+	return recent
+}
+
+// RecordSession remembers the players of a match session
+func (h *MatchHistory) RecordSession(sessionID string, playerIDs []string, at time.Time) {
+	if h == nil || sessionID == "" {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.sessions.put(sessionID, append([]string(nil), playerIDs...), at, h.capacity)
+}
+
+// SessionPlayers returns the last known players of a match session, or nil when the session is unknown
+func (h *MatchHistory) SessionPlayers(sessionID string, now time.Time) []string {
+	if h == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	players, _ := h.sessions.get(sessionID, now, h.ttl)
+
+	return append([]string(nil), players...)
+}
+
+func newExpiringLRU[V any]() expiringLRU[V] {
+	return expiringLRU[V]{
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the value of key, dropping it when it was last updated ttl or more before now
+func (l expiringLRU[V]) get(key string, now time.Time, ttl time.Duration) (V, bool) {
+	var zero V
+
+	element, ok := l.entries[key]
+	if !ok {
+		return zero, false
+	}
+
+	entry := element.Value.(*expiringEntry[V])
+	if now.Sub(entry.updated) >= ttl {
+		l.order.Remove(element)
+		delete(l.entries, key)
+
+		return zero, false
+	}
+
+	return entry.value, true
+}
+
+// put stores the value of key, evicting the least recently updated entries beyond capacity
+func (l expiringLRU[V]) put(key string, value V, at time.Time, capacity int) {
+	if element, ok := l.entries[key]; ok {
+		entry := element.Value.(*expiringEntry[V])
+		entry.value, entry.updated = value, at
+		l.order.MoveToFront(element)
+
+		return
+	}
+
+	l.entries[key] = l.order.PushFront(&expiringEntry[V]{key: key, value: value, updated: at})
+
+	for l.order.Len() > capacity {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.entries, oldest.Value.(*expiringEntry[V]).key)
+	}
+}
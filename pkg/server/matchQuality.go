@@ -0,0 +1,72 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"time"
+)
+
+// match attributes holding the quality diagnostics of a generated match
+const (
+	matchAttributeQualityScore = "quality_score"
+	matchAttributeMMRSpread    = "mmr_spread"
+	matchAttributeWorstLatency = "worst_latency_ms"
+	matchAttributeMaxWait      = "max_wait_seconds"
+)
+
+// matchQuality is the quality score of a match and the components it is computed from
+type matchQuality struct {
+	score        float64
+	spread       float64 // difference between the highest and lowest ticket value
+	imbalance    float64 // difference between the highest and lowest team value
+	worstLatency float64 // highest player latency to the preferred region in ms, 0 when unknown
+	maxWait      float64 // longest ticket wait in seconds
+}
+
+// measureMatchQuality computes the quality of a group split into teams at the given time
+func measureMatchQuality(group *matchGroup, teams [][]int, rules GameRules, now time.Time) matchQuality {
+	quality := matchQuality{
+		spread:    group.maxValue - group.minValue,
+		imbalance: teamImbalance(group.members, teams, rules.TeamBalance.GetMetric()),
+	}
+
+	if preference := regionPreference(group.regions); len(preference) > 0 {
+		quality.worstLatency = float64(group.regions[preference[0]])
+	}
+
+	for _, member := range group.members {
+		quality.maxWait = max(quality.maxWait, now.Sub(member.ticket.CreatedAt).Seconds())
+	}
+
+	// every component scores 1 when perfect and 0.5 at its reference value, the score is their average
+	quality.score = (qualityComponent(quality.spread, rules.Quality.GetSpreadReference()) +
+		qualityComponent(quality.imbalance, rules.Quality.GetImbalanceReference()) +
+		qualityComponent(quality.worstLatency, rules.Quality.GetLatencyReferenceMs()) +
+		qualityComponent(quality.maxWait, rules.Quality.GetWaitReferenceSeconds())) / 4
+
+	return quality
+}
+
+func qualityComponent(value, reference float64) float64 {
+	return reference / (reference + max(value, 0))
+}
+
+// setAttributes writes the score and its components into match attributes
+func (q matchQuality) setAttributes(attributes map[string]interface{}) {
+	attributes[matchAttributeQualityScore] = q.score
+	attributes[matchAttributeMMRSpread] = q.spread
+	attributes[matchAttributeTeamImbalance] = q.imbalance
+	attributes[matchAttributeWorstLatency] = q.worstLatency
+	attributes[matchAttributeMaxWait] = q.maxWait
+}
+
+// observe exports the score and its components to the match quality histograms
+func (q matchQuality) observe(matchPool string) {
+	matchQualityScore.WithLabelValues(matchPool).Observe(q.score)
+	matchMMRSpread.WithLabelValues(matchPool).Observe(q.spread)
+	matchTeamImbalance.WithLabelValues(matchPool).Observe(q.imbalance)
+	matchWorstLatency.WithLabelValues(matchPool).Observe(q.worstLatency)
+	matchMaxWait.WithLabelValues(matchPool).Observe(q.maxWait)
+}
@@ -5,8 +5,9 @@
 package server
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -15,22 +16,37 @@ import (
 	"matchmaking-function-grpc-plugin-server-go/pkg/matchmaker"
 )
 
+// MatchMakerConfig configures the MatchMaker returned by New
+type MatchMakerConfig struct {
+	// RulesBaseDir is the directory of the base rulesets, empty disables "extends"
+	RulesBaseDir string
+
+	// MatchHistorySize is the number of players and sessions the match history keeps, 0 disables it
+	MatchHistorySize int
+
+	// MatchHistoryTTL is how long the match history remembers a roster
+	MatchHistoryTTL time.Duration
+}
+
 // New returns a MatchMaker of the MatchLogic interface
-func New() MatchLogic {
-	return MatchMaker{}
+func New(config MatchMakerConfig) MatchLogic[GameRules] {
+	var bases BaseRulesets
+	if config.RulesBaseDir != "" {
+		bases = RulesDirectory(config.RulesBaseDir)
+	}
+
+	history := NewMatchHistory(config.MatchHistorySize, config.MatchHistoryTTL)
+
+	matchMaker := MatchMaker{BaseRulesets: bases, History: history}
+	matchMaker.sourcesVersion = newCachedVersion(matchMaker.baseRulesetsVersion, rulesSourcesCheckInterval)
+
+	return matchMaker
 }
 
 // GetStatCodes returns the stat codes configured in the rules
-func (b MatchMaker) GetStatCodes(scope *common.Scope, matchRules interface{}) []string {
+func (b MatchMaker) GetStatCodes(scope *common.Scope, rule GameRules) []string {
 	log := scope.Log.With("method", "MatchMaker.GetStatCodes")
 
-	rule, ok := matchRules.(GameRules)
-	if !ok {
-		log.Error("unexpected game rule type", "type", fmt.Sprintf("%T", matchRules))
-
-		return []string{}
-	}
-
 	// If no statistics configured, return empty
 	if len(rule.Statistics.Statistics) == 0 {
 		log.Info("no statistics configured, returning empty stat codes")
@@ -38,23 +54,17 @@ func (b MatchMaker) GetStatCodes(scope *common.Scope, matchRules interface{}) []
 		return []string{}
 	}
 
-	log.Info("returning stat codes", "codes", rule.Statistics.Statistics)
+	statCodes := rule.Statistics.StatCodes()
+	log.Info("returning stat codes", "codes", statCodes)
 
-	return rule.Statistics.Statistics
+	return statCodes
 }
 
 // ValidateTicket validates that the ticket has a valid selected stat
-func (b MatchMaker) ValidateTicket(scope *common.Scope, matchTicket matchmaker.Ticket, matchRules interface{}) (bool, error) {
+func (b MatchMaker) ValidateTicket(scope *common.Scope, matchTicket matchmaker.Ticket, rule GameRules) (bool, error) {
 	log := scope.Log.With("method", "MatchMaker.ValidateTicket", "ticketID", matchTicket.TicketID)
 	log.Info("validating ticket")
-	log.Info("ticket/rules snapshot", "ticket", matchTicket, "rules", matchRules)
-
-	rule, ok := matchRules.(GameRules)
-	if !ok {
-		log.Error("unexpected game rule type", "type", fmt.Sprintf("%T", matchRules))
-
-		return false, status.Error(codes.Internal, "invalid game rules type")
-	}
+	log.Info("ticket/rules snapshot", "ticket", matchTicket, "rules", rule)
 
 	// If no statistics configured, skip validation
 	if len(rule.Statistics.Statistics) == 0 {
@@ -84,18 +94,11 @@ func (b MatchMaker) ValidateTicket(scope *common.Scope, matchTicket matchmaker.T
 	return true, nil
 }
 
-// EnrichTicket extracts the selected stat value and adds it to ticket attributes
-func (b MatchMaker) EnrichTicket(scope *common.Scope, matchTicket matchmaker.Ticket, ruleSet interface{}) (matchmaker.Ticket, error) {
+// EnrichTicket extracts the selected stat value and adds it to ticket attributes, keeping the selected stat code
+func (b MatchMaker) EnrichTicket(scope *common.Scope, matchTicket matchmaker.Ticket, rule GameRules) (matchmaker.Ticket, error) {
 	log := scope.Log.With("method", "MatchMaker.EnrichTicket", "ticketID", matchTicket.TicketID)
 	log.Info("enriching ticket")
-	log.Info("ticket/rules snapshot", "ticket", matchTicket, "rules", ruleSet)
-
-	rule, ok := ruleSet.(GameRules)
-	if !ok {
-		log.Error("unexpected game rule type", "type", fmt.Sprintf("%T", ruleSet))
-
-		return matchTicket, status.Error(codes.Internal, "invalid game rules type")
-	}
+	log.Info("ticket/rules snapshot", "ticket", matchTicket, "rules", rule)
 
 	// If no statistics configured, skip enrichment
 	if len(rule.Statistics.Statistics) == 0 {
@@ -148,9 +151,18 @@ func (b MatchMaker) EnrichTicket(scope *common.Scope, matchTicket matchmaker.Tic
 			playerLog.Warn("player missing selected stat", "stat", selectedStat)
 		}
 
+		// Keep the selected stat, it stands for the player's character in the character rules
+		if selectedStat != "" {
+			if matchTicket.Players[i].Attributes == nil {
+				matchTicket.Players[i].Attributes = make(map[string]interface{})
+			}
+
+			matchTicket.Players[i].Attributes[rule.Statistics.GetSelectedStatKey()] = selectedStat
+		}
+
 		// Always remove configured statistics from player attributes
 		for _, stat := range rule.Statistics.Statistics {
-			delete(matchTicket.Players[i].Attributes, stat)
+			delete(matchTicket.Players[i].Attributes, stat.Code)
 		}
 	}
 
@@ -164,27 +176,109 @@ func (b MatchMaker) EnrichTicket(scope *common.Scope, matchTicket matchmaker.Tic
 	return matchTicket, nil
 }
 
-// RulesFromJSON returns the ruleset from the Game rules JSON
-func (b MatchMaker) RulesFromJSON(scope *common.Scope, jsonRules string) (interface{}, error) {
-	var ruleSet GameRules
-	err := json.Unmarshal([]byte(jsonRules), &ruleSet)
+// RulesSourcesVersion returns the version of the base rulesets when they can report one, so cached rules
+// extending an edited base ruleset are parsed again. A MatchMaker made by New computes it at most every
+// rulesSourcesCheckInterval.
+func (b MatchMaker) RulesSourcesVersion() string {
+	if b.sourcesVersion != nil {
+		return b.sourcesVersion.get(time.Now())
+	}
+
+	return b.baseRulesetsVersion()
+}
+
+func (b MatchMaker) baseRulesetsVersion() string {
+	if versioned, ok := b.BaseRulesets.(interface{ Version() string }); ok {
+		return versioned.Version()
+	}
+
+	return ""
+}
+
+// RulesFromJSON returns the validated ruleset from the Game rules JSON, migrated to CurrentRulesVersion
+func (b MatchMaker) RulesFromJSON(scope *common.Scope, jsonRules string) (GameRules, error) {
+	ruleSet, fromVersion, err := ParseGameRules(jsonRules, b.BaseRulesets)
 	if err != nil {
-		return nil, err
+		return GameRules{}, status.Errorf(codes.InvalidArgument, "invalid rules json: %v", err)
+	}
+
+	if problems := ruleSet.Validate(); len(problems) > 0 {
+		return GameRules{}, status.Errorf(codes.InvalidArgument, "invalid rules: %v", errors.Join(problems...))
+	}
+
+	// built once here so cached rules reuse the strategy
+	if ruleSet.strategy, err = ruleSet.MatchingStrategy(); err != nil {
+		return GameRules{}, status.Errorf(codes.InvalidArgument, "invalid rules: %v", err)
+	}
+
+	if fromVersion < CurrentRulesVersion {
+		scope.Log.Warn("rules use a deprecated schema version, update the ruleset to the current version",
+			"version", fromVersion,
+			"currentVersion", CurrentRulesVersion)
 	}
 
 	return ruleSet, nil
 }
 
-// MakeMatches returns nil to signal UNIMPLEMENTED - AGS will use default matching
-func (b MatchMaker) MakeMatches(scope *common.Scope, ticketProvider TicketProvider, matchRules interface{}) <-chan matchmaker.Match {
-	scope.Log.Info("MakeMatches not implemented, delegating to AGS default matching")
+// MakeMatches makes matches with the strategy selected by the rules, the MMR window strategy by default.
+// It returns nil to signal UNIMPLEMENTED when no alliance rule is configured - AGS will use default matching
+func (b MatchMaker) MakeMatches(scope *common.Scope, ticketProvider TicketProvider, matchRules GameRules) <-chan matchmaker.Match {
+	if !matchRules.Alliance.IsConfigured() {
+		scope.Log.Info("MakeMatches not configured, delegating to AGS default matching")
+
+		return nil
+	}
+
+	strategy, err := matchRules.MatchingStrategy()
+	if err != nil {
+		// rules from RulesFromJSON always have a strategy
+		scope.Log.Error("could not build the matching strategy", "error", err)
+
+		return nil
+	}
+
+	results := make(chan matchmaker.Match)
+
+	go func() {
+		defer close(results)
+
+		var tickets []matchmaker.Ticket
+		for ticket := range ticketProvider.GetTickets() {
+			tickets = append(tickets, ticket)
+		}
 
-	return nil
+		strategy.MakeMatches(scope, tickets, matchRules, b.History, results)
+	}()
+
+	return results
 }
 
-// BackfillMatches returns nil to signal UNIMPLEMENTED - AGS will use default backfill
-func (b MatchMaker) BackfillMatches(scope *common.Scope, ticketProvider TicketProvider, matchRules interface{}) <-chan matchmaker.BackfillProposal {
-	scope.Log.Info("BackfillMatches not implemented, delegating to AGS default backfill")
+// BackfillMatches proposes tickets close to the enriched stat average of the partial matches of the backfill tickets.
+// It returns nil to signal UNIMPLEMENTED when no alliance rule is configured - AGS will use default backfill
+func (b MatchMaker) BackfillMatches(scope *common.Scope, ticketProvider TicketProvider, matchRules GameRules) <-chan matchmaker.BackfillProposal {
+	if !matchRules.Alliance.IsConfigured() {
+		scope.Log.Info("BackfillMatches not configured, delegating to AGS default backfill")
+
+		return nil
+	}
+
+	results := make(chan matchmaker.BackfillProposal)
+
+	go func() {
+		defer close(results)
+
+		var backfillTickets []matchmaker.BackfillTicket
+		for backfillTicket := range ticketProvider.GetBackfillTickets() {
+			backfillTickets = append(backfillTickets, backfillTicket)
+		}
+
+		var tickets []matchmaker.Ticket
+		for ticket := range ticketProvider.GetTickets() {
+			tickets = append(tickets, ticket)
+		}
+
+		makeBackfillProposals(scope, backfillTickets, tickets, matchRules, b.History, results)
+	}()
 
-	return nil
+	return results
 }
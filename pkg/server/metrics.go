@@ -0,0 +1,80 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "matchfunction"
+
+var (
+	rulesCacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "rules_cache_requests_total",
+		Help:      "Number of parsed rules lookups, partitioned by result (hit or miss).",
+	}, []string{"result"})
+
+	tickReplays = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "tick_replays_total",
+		Help:      "Number of retried MakeMatches ticks answered with the matches remembered for their tickId.",
+	})
+
+	tickBudgetExhausted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "tick_budget_exhausted_total",
+		Help:      "Number of ticks that ran out of their time budget and returned the results found so far, partitioned by RPC.",
+	}, []string{"rpc"})
+
+	matchQualityScore = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "match_quality_score",
+		Help:      "Quality score of the generated matches, from 0 (worst) to 1 (best).",
+		Buckets:   prometheus.LinearBuckets(0.1, 0.1, 10),
+	}, []string{"match_pool"})
+
+	matchMMRSpread = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "match_mmr_spread",
+		Help:      "Difference between the highest and lowest ticket value of the generated matches.",
+		Buckets:   prometheus.ExponentialBuckets(10, 2, 10),
+	}, []string{"match_pool"})
+
+	matchTeamImbalance = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "match_team_imbalance",
+		Help:      "Difference between the highest and lowest team value of the generated matches.",
+		Buckets:   prometheus.ExponentialBuckets(10, 2, 10),
+	}, []string{"match_pool"})
+
+	matchWorstLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "match_worst_latency_milliseconds",
+		Help:      "Highest player latency to the preferred region of the generated matches.",
+		Buckets:   prometheus.ExponentialBuckets(10, 2, 9),
+	}, []string{"match_pool"})
+
+	matchMaxWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "match_max_wait_seconds",
+		Help:      "Longest ticket wait of the generated matches.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	}, []string{"match_pool"})
+)
+
+// Collectors returns the Prometheus collectors owned by the server package
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		rulesCacheRequests,
+		tickReplays,
+		tickBudgetExhausted,
+		matchQualityScore,
+		matchMMRSpread,
+		matchTeamImbalance,
+		matchWorstLatency,
+		matchMaxWait,
+	}
+}
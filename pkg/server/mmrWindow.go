@@ -0,0 +1,563 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"matchmaking-function-grpc-plugin-server-go/pkg/common"
+	"matchmaking-function-grpc-plugin-server-go/pkg/matchmaker"
+	"matchmaking-function-grpc-plugin-server-go/pkg/playerdata"
+)
+
+// matchAttributeTeamImbalance is the match attribute holding the difference between the highest and lowest team value
+const matchAttributeTeamImbalance = "team_imbalance"
+
+// matchCandidate is a ticket prepared for matching
+type matchCandidate struct {
+	ticket  matchmaker.Ticket
+	value   float64          // average enriched stat of the ticket players
+	regions map[string]int64 // regions the ticket accepts, with their latency
+	roles   [][]string       // roles each ticket player accepts, when role queueing is configured
+	avoid   map[string]bool  // IDs of the players the ticket must not be matched with
+
+	characters []string // character of each ticket player, the stat they selected
+
+	serverName    string // local DS name requested by the ticket
+	clientVersion string // game version of the ticket
+
+	compatibility []map[string]bool // value set of each compatibility rule, nil when the ticket does not set it
+}
+
+func (c matchCandidate) players() int {
+	return len(c.ticket.Players)
+}
+
+// matchGroup is a set of candidates being grown into a match
+type matchGroup struct {
+	rules    GameRules
+	members  []matchCandidate
+	players  int
+	minValue float64
+	maxValue float64
+	regions  map[string]int64 // regions every member accepts, with the worst member latency
+	ids      map[string]bool  // IDs of the member players
+	avoid    map[string]bool  // IDs of the players no member may be matched with
+
+	characters map[string]int // number of member players of each character
+	penalty    float64        // mirror match and soft compatibility penalties added to the spread
+
+	compatibility []map[string]bool // values every member agrees on for each compatibility rule
+}
+
+func newMatchGroup(anchor matchCandidate, rules GameRules) *matchGroup {
+	group := &matchGroup{
+		rules:    rules,
+		minValue: anchor.value,
+		maxValue: anchor.value,
+		regions:  anchor.regions,
+		ids:      make(map[string]bool),
+		avoid:    make(map[string]bool),
+
+		characters: make(map[string]int),
+
+		compatibility: make([]map[string]bool, len(rules.CompatibilityRules)),
+	}
+	group.add(anchor)
+
+	return group
+}
+
+// accepts reports whether candidate is compatible with every member of the group
+func (g *matchGroup) accepts(candidate matchCandidate) bool {
+	// tickets without latencies accept any region, the others must share at least one
+	if g.regions != nil && candidate.regions != nil && len(intersectRegions(g.regions, candidate.regions)) == 0 {
+		return false
+	}
+
+	for _, player := range candidate.ticket.Players {
+		if g.avoid[playerdata.IDToString(player.PlayerID)] {
+			return false
+		}
+	}
+	for id := range candidate.avoid {
+		if g.ids[id] {
+			return false
+		}
+	}
+
+	if candidate.serverName != g.members[0].serverName || candidate.clientVersion != g.members[0].clientVersion {
+		return false
+	}
+
+	for i, rule := range g.rules.CompatibilityRules {
+		if !rule.Soft && !compatible(g.compatibility[i], candidate.compatibility[i]) {
+			return false
+		}
+	}
+
+	// with unique characters per team, a character already in the group can only be a mirror
+	if g.rules.Characters.UniquePerTeam && g.rules.Characters.GetMirrorMatch() == mirrorMatchForbid {
+		for _, character := range candidate.characters {
+			if g.characters[character] > 0 {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// spreadWith returns the value spread of the group if candidate joined it, mirror match penalties included
+func (g *matchGroup) spreadWith(candidate matchCandidate) float64 {
+	return math.Max(g.maxValue, candidate.value) - math.Min(g.minValue, candidate.value) + g.penalty + g.penaltyWith(candidate)
+}
+
+// penaltyWith returns the mirror match penalty of the candidate characters already played in the group and
+// the penalty of the soft compatibility rules the candidate breaks. Teams are not split yet, so a character
+// repeated by a future teammate is penalized too. With unique_per_team the teams cannot hold it twice, so
+// only mirrors are.
+func (g *matchGroup) penaltyWith(candidate matchCandidate) float64 {
+	penalty := 0.0
+
+	if g.rules.Characters.GetMirrorMatch() == mirrorMatchPenalize {
+		for _, character := range candidate.characters {
+			if g.characters[character] > 0 {
+				penalty += g.rules.Characters.MirrorPenalty
+			}
+		}
+	}
+
+	for i, rule := range g.rules.CompatibilityRules {
+		if rule.Soft && !compatible(g.compatibility[i], candidate.compatibility[i]) {
+			penalty += rule.Penalty
+		}
+	}
+
+	return penalty
+}
+
+func (g *matchGroup) add(candidate matchCandidate) {
+	g.penalty += g.penaltyWith(candidate)
+	g.members = append(g.members, candidate)
+	g.players += candidate.players()
+	g.minValue = math.Min(g.minValue, candidate.value)
+	g.maxValue = math.Max(g.maxValue, candidate.value)
+	g.regions = intersectRegions(g.regions, candidate.regions)
+
+	for _, player := range candidate.ticket.Players {
+		g.ids[playerdata.IDToString(player.PlayerID)] = true
+	}
+	for id := range candidate.avoid {
+		g.avoid[id] = true
+	}
+	for _, character := range candidate.characters {
+		if character != "" {
+			g.characters[character]++
+		}
+	}
+	// a ticket breaking a soft rule leaves the values the other members agree on unchanged
+	for i := range g.compatibility {
+		if compatible(g.compatibility[i], candidate.compatibility[i]) {
+			g.compatibility[i] = intersectValues(g.compatibility[i], candidate.compatibility[i])
+		}
+	}
+}
+
+// playerIDs returns the IDs of every member player
+func (g *matchGroup) playerIDs() []string {
+	ids := make([]string, 0, g.players)
+	for _, member := range g.members {
+		for _, player := range member.ticket.Players {
+			ids = append(ids, playerdata.IDToString(player.PlayerID))
+		}
+	}
+
+	return ids
+}
+
+// makeMMRWindowMatches groups tickets whose enriched values are within the matching distance of each other
+// and fills teams according to the alliance rule. The longest waiting tickets anchor groups first and the
+// anchor age selects the flexing rules of its group. Tickets that cannot be placed are left for the next tick.
+// Players who recently played together according to history are kept apart and the rosters of the matches
+// made are recorded in it.
+func makeMMRWindowMatches(scope *common.Scope, tickets []matchmaker.Ticket, rules GameRules, history *MatchHistory, results chan<- matchmaker.Match) {
+	log := scope.Log.With("method", "makeMMRWindowMatches")
+
+	now := time.Now()
+	candidates := prepareCandidates(scope, tickets, rules, history, now)
+
+	made, done := growMatches(scope, candidates, rules, history, now, results)
+	if !done {
+		log.Info("matchmaking cancelled", "matches", made)
+
+		return
+	}
+
+	log.Info("matchmaking finished", "tickets", len(tickets), "matches", made)
+}
+
+// growMatches grows a group around every candidate, oldest first, and posts the groups that make a match.
+// It returns the number of matches posted and false when scope.Ctx was done before every candidate was visited.
+func growMatches(scope *common.Scope, candidates []matchCandidate, rules GameRules, history *MatchHistory, now time.Time, results chan<- matchmaker.Match) (int, bool) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].value < candidates[j].value
+	})
+
+	// anchors are visited oldest ticket first
+	anchors := make([]int, len(candidates))
+	for i := range anchors {
+		anchors[i] = i
+	}
+	sort.SliceStable(anchors, func(i, j int) bool {
+		return candidates[anchors[i]].ticket.CreatedAt.Before(candidates[anchors[j]].ticket.CreatedAt)
+	})
+
+	used := make([]bool, len(candidates))
+	made := 0
+
+	for _, anchor := range anchors {
+		if scope.Ctx.Err() != nil {
+			return made, false
+		}
+
+		if used[anchor] {
+			continue
+		}
+
+		waited := now.Sub(candidates[anchor].ticket.CreatedAt)
+		alliance := rules.AllianceAt(waited)
+
+		group, members := growGroup(candidates, used, anchor, matchingDistanceAt(rules, waited), alliance, rules)
+
+		teams, ok := assignTeams(scope, group.members, alliance, rules)
+		if !ok || !meetsAllianceMinimum(group.members, teams, alliance) {
+			continue
+		}
+
+		for _, member := range members {
+			used[member] = true
+		}
+
+		if !postMatch(scope, group, teams, rules, history, now, results) {
+			return made, false
+		}
+		made++
+	}
+
+	return made, true
+}
+
+// matchingDistanceAt returns the matching distance of a group anchored by a ticket that waited for waited,
+// infinite when no matching rule limits it
+func matchingDistanceAt(rules GameRules, waited time.Duration) float64 {
+	distance, limited := rules.MatchingDistanceAt(waited)
+	if !limited {
+		return math.Inf(1)
+	}
+
+	return distance
+}
+
+// postMatch builds the match of a group and posts it on results, recording its roster in history and its
+// quality in the metrics. It returns false when scope.Ctx was done before the match could be posted.
+func postMatch(scope *common.Scope, group *matchGroup, teams [][]int, rules GameRules, history *MatchHistory, now time.Time, results chan<- matchmaker.Match) bool {
+	quality := measureMatchQuality(group, teams, rules, now)
+
+	select {
+	case results <- buildMatch(group, teams, rules, quality):
+		history.RecordMatch(group.playerIDs(), now)
+		quality.observe(group.members[0].ticket.MatchPool)
+
+		return true
+	case <-scope.Ctx.Done():
+		return false
+	}
+}
+
+// prepareCandidates computes the enriched value of every ticket and the players it must avoid, discarding
+// tickets that cannot be matched
+func prepareCandidates(scope *common.Scope, tickets []matchmaker.Ticket, rules GameRules, history *MatchHistory, now time.Time) []matchCandidate {
+	enrichedKey := rules.Statistics.GetEnrichedKey()
+	candidates := make([]matchCandidate, 0, len(tickets))
+
+	for _, ticket := range tickets {
+		if len(ticket.Players) == 0 || len(ticket.Players) > rules.Alliance.PlayerMaxNumber {
+			scope.Log.Warn("discarding ticket that cannot fit a team", "ticketID", ticket.TicketID, "players", len(ticket.Players))
+
+			continue
+		}
+
+		value, ok := ticketValue(ticket, enrichedKey)
+		if !ok {
+			scope.Log.Warn("discarding ticket missing enriched attribute", "ticketID", ticket.TicketID, "key", enrichedKey)
+
+			continue
+		}
+
+		regions := acceptableRegions(ticket, rules.RegionLatencyMaxMs)
+		if rules.RegionLatencyMaxMs > 0 && len(regions) == 0 {
+			scope.Log.Warn("discarding ticket without a region within the latency limit", "ticketID", ticket.TicketID)
+
+			continue
+		}
+
+		var roles [][]string
+		if rules.Roles.IsConfigured() {
+			if roles, ok = playerRoles(ticket, rules.Roles); !ok {
+				scope.Log.Warn("discarding ticket with a player without a role of the composition", "ticketID", ticket.TicketID, "key", rules.Roles.GetAttribute())
+
+				continue
+			}
+		}
+
+		characters := playerCharacters(ticket, rules.Statistics.GetSelectedStatKey())
+		if rules.Characters.UniquePerTeam && hasDuplicateCharacter(characters) {
+			scope.Log.Warn("discarding ticket with a character picked twice", "ticketID", ticket.TicketID)
+
+			continue
+		}
+
+		candidates = append(candidates, matchCandidate{
+			ticket:     ticket,
+			value:      value,
+			regions:    regions,
+			roles:      roles,
+			avoid:      avoidedPlayers(ticket, rules, history, now),
+			characters: characters,
+
+			serverName:    stringTicketAttribute(ticket, rules.ServerSelection.ServerNameAttribute),
+			clientVersion: stringTicketAttribute(ticket, rules.ServerSelection.ClientVersionAttribute),
+
+			compatibility: compatibilityValues(ticket, rules.CompatibilityRules),
+		})
+	}
+
+	return candidates
+}
+
+// avoidedPlayers returns the IDs of the players a ticket must not be matched with: those who played with
+// its players within the rematch window and the known players of its excluded sessions
+func avoidedPlayers(ticket matchmaker.Ticket, rules GameRules, history *MatchHistory, now time.Time) map[string]bool {
+	avoid := make(map[string]bool)
+
+	window := time.Duration(rules.RematchWindow) * time.Second
+	for _, player := range ticket.Players {
+		for _, id := range history.RecentCoPlayers(playerdata.IDToString(player.PlayerID), window, now) {
+			avoid[id] = true
+		}
+	}
+
+	for _, sessionID := range ticket.ExcludedSessions {
+		for _, id := range history.SessionPlayers(sessionID, now) {
+			avoid[id] = true
+		}
+	}
+
+	// party members keep playing together
+	for _, player := range ticket.Players {
+		delete(avoid, playerdata.IDToString(player.PlayerID))
+	}
+
+	return avoid
+}
+
+// ticketValue returns the average of a numeric player attribute across the ticket players
+func ticketValue(ticket matchmaker.Ticket, key string) (float64, bool) {
+	if len(ticket.Players) == 0 {
+		return 0, false
+	}
+
+	total := 0.0
+	for _, player := range ticket.Players {
+		value, ok := numericAttribute(player.Attributes[key])
+		if !ok {
+			return 0, false
+		}
+		total += value
+	}
+
+	return total / float64(len(ticket.Players)), true
+}
+
+func numericAttribute(raw interface{}) (float64, bool) {
+	switch v := raw.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// growGroup grows a group around the anchor with the unused candidates closest in value, as long as the
+// group spread stays within distance, they are compatible with the group and its tickets still fit the teams.
+// candidates must be sorted by value. It returns the group and the indexes of its members.
+func growGroup(candidates []matchCandidate, used []bool, anchor int, distance float64, alliance AllianceRule, rules GameRules) (*matchGroup, []int) {
+	group := newMatchGroup(candidates[anchor], rules)
+	members := []int{anchor}
+	capacity := alliance.MaxPlayers()
+
+	below, above := anchor-1, anchor+1
+	for group.players < capacity {
+		// visit candidates by increasing distance to the anchor, so the first one too far ends the search
+		var next int
+		switch {
+		case below < 0 && above >= len(candidates):
+			next = -1
+		case below < 0:
+			next, above = above, above+1
+		case above >= len(candidates):
+			next, below = below, below-1
+		case candidates[anchor].value-candidates[below].value <= candidates[above].value-candidates[anchor].value:
+			next, below = below, below-1
+		default:
+			next, above = above, above+1
+		}
+
+		if next < 0 || math.Abs(candidates[next].value-candidates[anchor].value) > distance {
+			break
+		}
+
+		candidate := candidates[next]
+		if used[next] || group.players+candidate.players() > capacity || group.spreadWith(candidate) > distance || !group.accepts(candidate) {
+			continue
+		}
+
+		if !fitsTeams(append(group.members[:len(group.members):len(group.members)], candidate), alliance, newTeamConstraints(rules, false)) {
+			continue
+		}
+
+		group.add(candidate)
+		members = append(members, next)
+	}
+
+	return group, members
+}
+
+// packTeams places every candidate on one of the alliance teams without splitting tickets, largest tickets
+// first. It returns the candidate indexes of each team and false when some ticket does not fit.
+func packTeams(members []matchCandidate, alliance AllianceRule) ([][]int, bool) {
+	order := make([]int, len(members))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return members[order[i]].players() > members[order[j]].players()
+	})
+
+	teams := make([][]int, alliance.MaxNumber)
+	sizes := make([]int, alliance.MaxNumber)
+
+	for _, member := range order {
+		placed := false
+		for team := range teams {
+			if sizes[team]+members[member].players() <= alliance.PlayerMaxNumber {
+				teams[team] = append(teams[team], member)
+				sizes[team] += members[member].players()
+				placed = true
+
+				break
+			}
+		}
+
+		if !placed {
+			return nil, false
+		}
+	}
+
+	return teams, true
+}
+
+// fitsTeams reports whether the candidates can be placed on the alliance teams following the constraints
+func fitsTeams(members []matchCandidate, alliance AllianceRule, constraints teamConstraints) bool {
+	if constraints.active() {
+		return constrainedTeams(members, alliance, constraints, false) != nil
+	}
+
+	_, fits := packTeams(members, alliance)
+
+	return fits
+}
+
+// meetsAllianceMinimum reports whether the filled teams satisfy the alliance minimum team count and team size
+func meetsAllianceMinimum(members []matchCandidate, teams [][]int, alliance AllianceRule) bool {
+	filled := 0
+	for _, team := range teams {
+		size := 0
+		for _, member := range team {
+			size += members[member].players()
+		}
+
+		if size == 0 {
+			continue
+		}
+		if size < alliance.PlayerMinNumber {
+			return false
+		}
+		filled++
+	}
+
+	return filled >= alliance.MinNumber
+}
+
+// buildMatch turns a group and its team assignment into a match with its quality diagnostics, empty teams
+// are left out
+func buildMatch(group *matchGroup, teams [][]int, rules GameRules, quality matchQuality) matchmaker.Match {
+	regions := regionPreference(group.regions)
+	preferred := ""
+	if len(regions) > 0 {
+		preferred = regions[0]
+	}
+
+	anchor := group.members[0]
+	match := matchmaker.Match{
+		MatchAttributes:  map[string]interface{}{},
+		Backfill:         rules.AutoBackfill && group.players < rules.Alliance.MaxPlayers(),
+		RegionPreference: regions,
+		ServerName:       anchor.serverName,
+		ClientVersion:    anchor.clientVersion,
+
+		ServerPoolSelectionParameter: serverPoolSelection(rules.ServerSelection, preferred, anchor.clientVersion),
+	}
+	quality.setAttributes(match.MatchAttributes)
+
+	if rules.Roles.IsConfigured() {
+		match.MatchAttributes[matchAttributeRoles] = matchRoles(group.members, teams, rules.Roles)
+	}
+
+	for _, member := range group.members {
+		match.Tickets = append(match.Tickets, member.ticket)
+	}
+
+	for _, team := range teams {
+		if len(team) == 0 {
+			continue
+		}
+
+		matchTeam := matchmaker.Team{TeamID: common.GenerateUUID()}
+		for _, member := range team {
+			ticket := group.members[member].ticket
+			userIDs := make([]string, 0, len(ticket.Players))
+			for _, player := range ticket.Players {
+				matchTeam.UserIDs = append(matchTeam.UserIDs, player.PlayerID)
+				userIDs = append(userIDs, playerdata.IDToString(player.PlayerID))
+			}
+
+			if ticket.PartySessionID != "" {
+				matchTeam.Parties = append(matchTeam.Parties, matchmaker.Party{PartyID: ticket.PartySessionID, UserIDs: userIDs})
+			}
+		}
+
+		match.Teams = append(match.Teams, matchTeam)
+	}
+
+	return match
+}
@@ -0,0 +1,301 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"matchmaking-function-grpc-plugin-server-go/pkg/common"
+	"matchmaking-function-grpc-plugin-server-go/pkg/matchmaker"
+)
+
+const strategyOptimalPairing = "optimal_pairing"
+
+// defaultUnpairedCost is the cost of leaving a ticket unpaired when neither the options nor a matching rule set it
+const defaultUnpairedCost = 1000
+
+func init() {
+	RegisterStrategy(strategyOptimalPairing, newOptimalPairingStrategy)
+}
+
+// optimalPairingStrategy pairs the tickets of a 1v1 pool to minimize, over the whole tick, the enriched value
+// distance of the pairs plus a cost for every ticket left unpaired that grows with its wait. Pairs breaking
+// the other rules and unpaired tickets are then matched by the MMR window matcher.
+type optimalPairingStrategy struct {
+	// UnpairedCost is the cost of leaving a ticket unpaired for the tick, in enriched stat distance.
+	// 0 uses the matching distance, or defaultUnpairedCost when there is none.
+	UnpairedCost float64 `json:"unpaired_cost"`
+
+	// WaitWeight is added to the unpaired cost of a ticket for every second it waited
+	WaitWeight float64 `json:"wait_weight"`
+}
+
+func newOptimalPairingStrategy(options json.RawMessage) (MatchingStrategy, error) {
+	strategy := optimalPairingStrategy{WaitWeight: 1}
+	if err := DecodeStrategyOptions(options, &strategy); err != nil {
+		return nil, err
+	}
+
+	if strategy.UnpairedCost < 0 {
+		return nil, fmt.Errorf("unpaired_cost: must not be negative")
+	}
+	if strategy.WaitWeight < 0 {
+		return nil, fmt.Errorf("wait_weight: must not be negative")
+	}
+
+	return strategy, nil
+}
+
+func (s optimalPairingStrategy) Validate(rules GameRules) []error {
+	if rules.Alliance.MaxNumber != 2 || rules.Alliance.PlayerMaxNumber != 1 {
+		return []error{fmt.Errorf("alliance: the %s strategy requires max_number 2 and player_max_number 1", strategyOptimalPairing)}
+	}
+
+	return nil
+}
+
+func (s optimalPairingStrategy) MakeMatches(scope *common.Scope, tickets []matchmaker.Ticket, rules GameRules, history *MatchHistory, results chan<- matchmaker.Match) {
+	log := scope.Log.With("method", "optimalPairingStrategy.MakeMatches")
+
+	now := time.Now()
+	candidates := prepareCandidates(scope, tickets, rules, history, now)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].value < candidates[j].value
+	})
+
+	pairs := s.pair(candidates, rules, now)
+
+	// the longest waiting pairs are posted first, they matter most when the tick budget runs out
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return oldestOf(candidates, pairs[i]).Before(oldestOf(candidates, pairs[j]))
+	})
+
+	used := make([]bool, len(candidates))
+	made := 0
+
+	for _, pair := range pairs {
+		if scope.Ctx.Err() != nil {
+			log.Info("matchmaking cancelled", "matches", made)
+
+			return
+		}
+
+		waited := now.Sub(oldestOf(candidates, pair))
+		alliance := rules.AllianceAt(waited)
+
+		group := newMatchGroup(candidates[pair[0]], rules)
+		other := candidates[pair[1]]
+		if !group.accepts(other) || group.spreadWith(other) > matchingDistanceAt(rules, waited) {
+			continue
+		}
+		group.add(other)
+
+		teams, ok := assignTeams(scope, group.members, alliance, rules)
+		if !ok || !meetsAllianceMinimum(group.members, teams, alliance) {
+			continue
+		}
+
+		used[pair[0]], used[pair[1]] = true, true
+
+		if !postMatch(scope, group, teams, rules, history, now, results) {
+			log.Info("matchmaking cancelled", "matches", made)
+
+			return
+		}
+		made++
+	}
+
+	var leftovers []matchCandidate
+	for i, candidate := range candidates {
+		if !used[i] {
+			leftovers = append(leftovers, candidate)
+		}
+	}
+
+	more, done := growMatches(scope, leftovers, rules, history, now, results)
+	if !done {
+		log.Info("matchmaking cancelled", "matches", made+more)
+
+		return
+	}
+
+	log.Info("matchmaking finished", "tickets", len(tickets), "pairs", made, "matches", made+more)
+}
+
+// pair returns the lowest cost pairs of candidates sorted by value. Two tickets can only be paired within the
+// flexed matching distance of one of them. Pairs never cross nor nest in value order, which only loses to
+// nested pairs when the matching distances of the tickets differ, so a dynamic program over the sorted
+// candidates finds them in O(n log n): the cost of the first i candidates is either the cost of the first
+// i-1 plus leaving candidate i-1 unpaired, or the cost of the first j plus pairing candidate j with candidate
+// i-1 and leaving the ones between unpaired.
+func (s optimalPairingStrategy) pair(candidates []matchCandidate, rules GameRules, now time.Time) [][2]int {
+	n := len(candidates)
+	if n < 2 {
+		return nil
+	}
+
+	unpairedCost := s.UnpairedCost
+	if unpairedCost == 0 {
+		if distance, limited := rules.MatchingDistance(); limited && distance > 0 {
+			unpairedCost = distance
+		} else {
+			unpairedCost = defaultUnpairedCost
+		}
+	}
+
+	values := make([]float64, n)
+	distances := make([]float64, n)
+	skipped := make([]float64, n+1) // skipped[i] is the cost of leaving the first i candidates unpaired
+	for i, candidate := range candidates {
+		waited := now.Sub(candidate.ticket.CreatedAt)
+		values[i] = candidate.value
+		distances[i] = matchingDistanceAt(rules, waited)
+		skipped[i+1] = skipped[i] + unpairedCost + s.WaitWeight*math.Max(waited.Seconds(), 0)
+	}
+
+	// the cost of pairing j with t is key(j) + values[t] + skipped[t], key(j) = cost[j] - values[j] - skipped[j+1]
+	cost := make([]float64, n+1)
+	partner := make([]int, n+1) // partner[i] pairs candidate i-1 with it, -1 leaves it unpaired
+	keys := newMinTree(n)
+	byReach := &reachHeap{} // candidates that can reach any ticket up to their own matching distance
+
+	for t := 0; t < n; t++ {
+		if t > 0 {
+			j := t - 1
+			key := cost[j] - values[j] - skipped[j+1]
+			keys.set(j, key)
+			heap.Push(byReach, reachEntry{key: key, reach: values[j] + distances[j], index: j})
+		}
+
+		cost[t+1], partner[t+1] = cost[t]+skipped[t+1]-skipped[t], -1
+
+		// partners within the matching distance of t
+		lowest := sort.SearchFloat64s(values[:t], values[t]-distances[t])
+		if key, j := keys.min(lowest, t); j >= 0 && key+values[t]+skipped[t] < cost[t+1] {
+			cost[t+1], partner[t+1] = key+values[t]+skipped[t], j
+		}
+
+		// partners whose own matching distance reaches t, the others never reach a later candidate either
+		for byReach.Len() > 0 && (*byReach)[0].reach < values[t] {
+			heap.Pop(byReach)
+		}
+		if byReach.Len() > 0 {
+			if best := (*byReach)[0]; best.key+values[t]+skipped[t] < cost[t+1] {
+				cost[t+1], partner[t+1] = best.key+values[t]+skipped[t], best.index
+			}
+		}
+	}
+
+	var pairs [][2]int
+	for i := n; i > 0; {
+		if partner[i] < 0 {
+			i--
+
+			continue
+		}
+
+		pairs = append(pairs, [2]int{partner[i], i - 1})
+		i = partner[i]
+	}
+
+	return pairs
+}
+
+// oldestOf returns the creation time of the oldest ticket of a pair
+func oldestOf(candidates []matchCandidate, pair [2]int) time.Time {
+	a, b := candidates[pair[0]].ticket.CreatedAt, candidates[pair[1]].ticket.CreatedAt
+	if b.Before(a) {
+		return b
+	}
+
+	return a
+}
+
+// minTree is a segment tree answering the minimum value over a range of indexes
+type minTree struct {
+	size   int
+	values []float64
+	index  []int
+}
+
+func newMinTree(n int) *minTree {
+	size := 1
+	for size < n {
+		size *= 2
+	}
+
+	tree := &minTree{size: size, values: make([]float64, 2*size), index: make([]int, 2*size)}
+	for i := range tree.values {
+		tree.values[i], tree.index[i] = math.Inf(1), -1
+	}
+
+	return tree
+}
+
+func (t *minTree) set(i int, value float64) {
+	node := t.size + i
+	t.values[node], t.index[node] = value, i
+
+	for node /= 2; node > 0; node /= 2 {
+		left, right := 2*node, 2*node+1
+		if t.values[right] < t.values[left] {
+			t.values[node], t.index[node] = t.values[right], t.index[right]
+		} else {
+			t.values[node], t.index[node] = t.values[left], t.index[left]
+		}
+	}
+}
+
+// min returns the minimum value over [from, to) and its index, -1 when the range holds no value
+func (t *minTree) min(from, to int) (float64, int) {
+	best, bestIndex := math.Inf(1), -1
+
+	for left, right := from+t.size, to+t.size; left < right; left, right = left/2, right/2 {
+		if left%2 == 1 {
+			if t.values[left] < best {
+				best, bestIndex = t.values[left], t.index[left]
+			}
+			left++
+		}
+		if right%2 == 1 {
+			right--
+			if t.values[right] < best {
+				best, bestIndex = t.values[right], t.index[right]
+			}
+		}
+	}
+
+	return best, bestIndex
+}
+
+type reachEntry struct {
+	key   float64
+	reach float64 // highest value the candidate can be paired with
+	index int
+}
+
+// reachHeap is a min-heap of reachEntry by key
+type reachHeap []reachEntry
+
+func (h reachHeap) Len() int           { return len(h) }
+func (h reachHeap) Less(i, j int) bool { return h[i].key < h[j].key }
+func (h reachHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *reachHeap) Push(x interface{}) {
+	*h = append(*h, x.(reachEntry))
+}
+
+func (h *reachHeap) Pop() interface{} {
+	old := *h
+	entry := old[len(old)-1]
+	*h = old[:len(old)-1]
+
+	return entry
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"matchmaking-function-grpc-plugin-server-go/pkg/matchmaker"
+)
+
+// pairingRules returns 1v1 rules pairing tickets within distance of each other
+func pairingRules(distance float64) GameRules {
+	rules := GameRules{Alliance: AllianceRule{MinNumber: 2, MaxNumber: 2, PlayerMinNumber: 1, PlayerMaxNumber: 1}}
+	rules.MatchingRules = []MatchingRule{{Attribute: rules.Statistics.GetEnrichedKey(), Criteria: "distance", Reference: distance}}
+
+	return rules
+}
+
+// pairingTickets returns solo tickets with the given values and waits
+func pairingTickets(values []float64, waits []time.Duration) []matchmaker.Ticket {
+	tickets := make([]matchmaker.Ticket, len(values))
+	for i, value := range values {
+		tickets[i] = backfillTestTicket(fmt.Sprintf("ticket-%d", i), value, waits[i], "")
+	}
+
+	return tickets
+}
+
+// runStrategy returns the matches strategy makes from tickets
+func runStrategy(strategy MatchingStrategy, tickets []matchmaker.Ticket, rules GameRules) []matchmaker.Match {
+	// a 1v1 match takes two tickets, so the strategy never blocks on results
+	results := make(chan matchmaker.Match, len(tickets)/2+1)
+	strategy.MakeMatches(testScope(), tickets, rules, nil, results)
+	close(results)
+
+	var matches []matchmaker.Match
+	for match := range results {
+		matches = append(matches, match)
+	}
+
+	return matches
+}
+
+// matchValueGap returns the difference between the values of the two tickets of a 1v1 match
+func matchValueGap(match matchmaker.Match, rules GameRules) float64 {
+	first, _ := ticketValue(match.Tickets[0], rules.Statistics.GetEnrichedKey())
+	second, _ := ticketValue(match.Tickets[1], rules.Statistics.GetEnrichedKey())
+
+	return math.Abs(first - second)
+}
+
+func TestOptimalPairingBeatsMMRWindowOnOutliers(t *testing.T) {
+	rules := pairingRules(10)
+
+	// the oldest ticket sits between two outliers: taking its closest partner strands both of them
+	tickets := pairingTickets(
+		[]float64{100, 108, 109, 118},
+		[]time.Duration{time.Second, 5 * time.Second, time.Second, time.Second},
+	)
+
+	if matches := runStrategy(mmrWindowStrategy{}, tickets, rules); len(matches) != 1 {
+		t.Fatalf("mmr_window made %d matches, want 1: %v", len(matches), matches)
+	}
+
+	matches := runStrategy(optimalPairingStrategy{WaitWeight: 1}, tickets, rules)
+	if len(matches) != 2 {
+		t.Fatalf("optimal_pairing made %d matches, want 2: %v", len(matches), matches)
+	}
+
+	for _, match := range matches {
+		if gap := matchValueGap(match, rules); gap > 10 {
+			t.Errorf("match %v is %v apart, beyond the matching distance", match.Tickets, gap)
+		}
+	}
+}
+
+// BenchmarkMakeMatches1v1 runs a whole tick of a 1v1 pool, candidate preparation, pairing, posting and the
+// mmr_window fallback included, for optimal_pairing and for the mmr_window strategy it improves on
+func BenchmarkMakeMatches1v1(b *testing.B) {
+	rules := pairingRules(50)
+	rules.FlexingRules = []FlexingRule{{Duration: 30, Attribute: rules.Statistics.GetEnrichedKey(), Criteria: "distance", Reference: 200}}
+
+	strategies := []struct {
+		name     string
+		strategy MatchingStrategy
+	}{
+		{strategyOptimalPairing, optimalPairingStrategy{WaitWeight: 1}},
+		{strategyMMRWindow, mmrWindowStrategy{}},
+	}
+
+	for _, size := range []int{1000, 10000, 50000} {
+		random := rand.New(rand.NewSource(int64(size)))
+		values := make([]float64, size)
+		waits := make([]time.Duration, size)
+		for i := range values {
+			values[i] = 1500 + random.NormFloat64()*300
+			waits[i] = time.Duration(random.Intn(60)) * time.Second
+		}
+		tickets := pairingTickets(values, waits)
+
+		for _, s := range strategies {
+			b.Run(fmt.Sprintf("strategy=%s/tickets=%d", s.name, size), func(b *testing.B) {
+				matches := 0
+				for range b.N {
+					matches = len(runStrategy(s.strategy, tickets, rules))
+				}
+				b.ReportMetric(float64(matches), "matches")
+			})
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"sort"
+
+	"matchmaking-function-grpc-plugin-server-go/pkg/matchmaker"
+)
+
+// acceptableRegions returns the regions of a ticket with their latency, keeping only the regions within
+// maxLatency when it is positive. It returns nil, meaning any region, when the ticket reports no latencies.
+func acceptableRegions(ticket matchmaker.Ticket, maxLatency int64) map[string]int64 {
+	if len(ticket.Latencies) == 0 {
+		return nil
+	}
+
+	regions := make(map[string]int64, len(ticket.Latencies))
+	for region, latency := range ticket.Latencies {
+		if maxLatency > 0 && latency > maxLatency {
+			continue
+		}
+		regions[region] = latency
+	}
+
+	return regions
+}
+
+// intersectRegions returns the regions present in both sets with the worst of the two latencies.
+// A nil set accepts any region.
+func intersectRegions(a, b map[string]int64) map[string]int64 {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+
+	regions := make(map[string]int64, len(a))
+	for region, latency := range a {
+		if other, ok := b[region]; ok {
+			regions[region] = max(latency, other)
+		}
+	}
+
+	return regions
+}
+
+// regionPreference orders regions by their worst player latency, lowest first
+func regionPreference(regions map[string]int64) []string {
+	if len(regions) == 0 {
+		return nil
+	}
+
+	ordered := make([]string, 0, len(regions))
+	for region := range regions {
+		ordered = append(ordered, region)
+	}
+
+	sort.Slice(ordered, func(i, j int) bool {
+		if regions[ordered[i]] != regions[ordered[j]] {
+			return regions[ordered[i]] < regions[ordered[j]]
+		}
+
+		return ordered[i] < ordered[j]
+	})
+
+	return ordered
+}
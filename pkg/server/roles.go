@@ -0,0 +1,150 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"matchmaking-function-grpc-plugin-server-go/pkg/matchmaker"
+	"matchmaking-function-grpc-plugin-server-go/pkg/playerdata"
+)
+
+// matchAttributeRoles is the match attribute mapping each player ID to the role the player was given
+const matchAttributeRoles = "roles"
+
+// playerRoles reads the roles each ticket player accepts from the ticket attribute, in player order.
+// Roles outside the composition are ignored, it returns false when a player has none left.
+func playerRoles(ticket matchmaker.Ticket, rule RoleRule) ([][]string, bool) {
+	declared, _ := ticket.TicketAttributes[rule.GetAttribute()].(map[string]interface{})
+
+	roles := make([][]string, len(ticket.Players))
+	for i, player := range ticket.Players {
+		for _, role := range roleList(declared[playerdata.IDToString(player.PlayerID)]) {
+			if rule.Composition[role] > 0 {
+				roles[i] = append(roles[i], role)
+			}
+		}
+
+		if len(roles[i]) == 0 {
+			return nil, false
+		}
+	}
+
+	return roles, true
+}
+
+// roleList accepts a single role or a list of roles
+func roleList(raw interface{}) []string {
+	switch v := raw.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		roles := make([]string, 0, len(v))
+		for _, item := range v {
+			if role, ok := item.(string); ok {
+				roles = append(roles, role)
+			}
+		}
+
+		return roles
+	default:
+		return nil
+	}
+}
+
+// assignRoles gives every player one of the roles they accept without exceeding the count of each role,
+// moving already placed players along augmenting paths when needed. It returns the role of each player and
+// false when no such assignment exists.
+func assignRoles(players [][]string, counts map[string]int) ([]string, bool) {
+	assigned := make([]string, len(players))
+	holders := make(map[string][]int, len(counts))
+
+	var augment func(player int, visited map[string]bool) bool
+	augment = func(player int, visited map[string]bool) bool {
+		for _, role := range players[player] {
+			if visited[role] {
+				continue
+			}
+			visited[role] = true
+
+			if len(holders[role]) < counts[role] {
+				holders[role] = append(holders[role], player)
+				assigned[player] = role
+
+				return true
+			}
+
+			for i, holder := range holders[role] {
+				if augment(holder, visited) {
+					holders[role][i] = player
+					assigned[player] = role
+
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+
+	for player := range players {
+		if !augment(player, map[string]bool{}) {
+			return nil, false
+		}
+	}
+
+	return assigned, true
+}
+
+// teamPlayerRoles returns the accepted roles of every player of a team
+func teamPlayerRoles(members []matchCandidate, team []int) [][]string {
+	var roles [][]string
+	for _, member := range team {
+		roles = append(roles, members[member].roles...)
+	}
+
+	return roles
+}
+
+// rolesComplete reports whether every filled team has exactly the players of the composition
+func rolesComplete(members []matchCandidate, teams [][]int, rule RoleRule) bool {
+	for _, team := range teams {
+		if len(team) == 0 {
+			continue
+		}
+
+		players := teamPlayerRoles(members, team)
+		if len(players) != rule.TeamSize() {
+			return false
+		}
+		if _, ok := assignRoles(players, rule.Composition); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchRoles maps the ID of every player of a match to the role the player takes in its team
+func matchRoles(members []matchCandidate, teams [][]int, rule RoleRule) map[string]interface{} {
+	roles := make(map[string]interface{})
+
+	for _, team := range teams {
+		assigned, ok := assignRoles(teamPlayerRoles(members, team), rule.Composition)
+		if !ok {
+			continue
+		}
+
+		i := 0
+		for _, member := range team {
+			for _, player := range members[member].ticket.Players {
+				roles[playerdata.IDToString(player.PlayerID)] = assigned[i]
+				i++
+			}
+		}
+	}
+
+	return roles
+}
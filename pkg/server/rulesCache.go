@@ -0,0 +1,143 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// rulesSourcesCheckInterval is how long the version of the rules sources is reused before it is computed again
+const rulesSourcesCheckInterval = 5 * time.Second
+
+// RulesCache is a bounded LRU cache of parsed rules of type R keyed by the hash of the rules JSON and of the
+// version of the sources they depend on.
+// It is safe for concurrent use.
+type RulesCache[R any] struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // front is the most recently used entry
+	entries  map[[sha256.Size]byte]*list.Element
+}
+
+type rulesCacheEntry[R any] struct {
+	key   [sha256.Size]byte
+	rules R
+}
+
+// NewRulesCache returns a RulesCache holding at most capacity parsed rulesets.
+// A capacity below 1 disables caching.
+func NewRulesCache[R any](capacity int) *RulesCache[R] {
+	return &RulesCache[R]{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[[sha256.Size]byte]*list.Element),
+	}
+}
+
+// GetOrParse returns the cached rules for jsonRules, calling parse and caching its result on a miss.
+// Parse errors are never cached.
+func (c *RulesCache[R]) GetOrParse(jsonRules string, parse func(string) (R, error)) (R, error) {
+	return c.GetOrParseWithSources(jsonRules, "", parse)
+}
+
+// GetOrParseWithSources is GetOrParse for rules that also depend on data outside the JSON, such as base
+// rulesets. sources must change whenever that data does, it is part of the cache key.
+func (c *RulesCache[R]) GetOrParseWithSources(jsonRules, sources string, parse func(string) (R, error)) (R, error) {
+	if c == nil || c.capacity < 1 {
+		return parse(jsonRules)
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(sources))
+	hash.Write([]byte{0})
+	hash.Write([]byte(jsonRules))
+
+	var key [sha256.Size]byte
+	hash.Sum(key[:0])
+
+	if rules, ok := c.get(key); ok {
+		rulesCacheRequests.WithLabelValues("hit").Inc()
+
+		return rules, nil
+	}
+
+	rulesCacheRequests.WithLabelValues("miss").Inc()
+
+	// parse outside the lock, concurrent misses on the same key only cost a duplicate parse
+	rules, err := parse(jsonRules)
+	if err != nil {
+		return rules, err
+	}
+
+	c.put(key, rules)
+
+	return rules, nil
+}
+
+func (c *RulesCache[R]) get(key [sha256.Size]byte) (R, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		var zero R
+
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+
+	return element.Value.(*rulesCacheEntry[R]).rules, true
+}
+
+func (c *RulesCache[R]) put(key [sha256.Size]byte, rules R) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*rulesCacheEntry[R]).rules = rules
+		c.order.MoveToFront(element)
+
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&rulesCacheEntry[R]{key: key, rules: rules})
+
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*rulesCacheEntry[R]).key)
+	}
+}
+
+// cachedVersion reuses a computed version for a while, so sources like a directory are not read on every RPC
+type cachedVersion struct {
+	mu       sync.Mutex
+	compute  func() string
+	interval time.Duration
+	version  string
+	expires  time.Time
+}
+
+func newCachedVersion(compute func() string, interval time.Duration) *cachedVersion {
+	return &cachedVersion{compute: compute, interval: interval}
+}
+
+// get returns the version, computing it again when the last one is older than the interval
+func (v *cachedVersion) get(now time.Time) string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if now.Before(v.expires) {
+		return v.version
+	}
+
+	v.version = v.compute()
+	v.expires = now.Add(v.interval)
+
+	return v.version
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// countingParse returns a parse func counting its calls per rules JSON, it fails on "bad"
+func countingParse(calls map[string]int) func(string) (string, error) {
+	return func(json string) (string, error) {
+		calls[json]++
+		if json == "bad" {
+			return "", errors.New("bad rules")
+		}
+
+		return "parsed " + json, nil
+	}
+}
+
+func TestRulesCacheEvictsTheLeastRecentlyUsed(t *testing.T) {
+	calls := map[string]int{}
+	parse := countingParse(calls)
+	cache := NewRulesCache[string](2)
+
+	for _, json := range []string{"a", "b", "a", "c", "a", "b"} {
+		rules, err := cache.GetOrParse(json, parse)
+		if err != nil || rules != "parsed "+json {
+			t.Fatalf("GetOrParse(%q) = %q, %v", json, rules, err)
+		}
+	}
+
+	// "c" evicts "b", the least recently used after "a" was read again, and "b" then evicts "c"
+	if calls["a"] != 1 || calls["b"] != 2 || calls["c"] != 1 {
+		t.Errorf("parse calls = %v, want a:1 b:2 c:1", calls)
+	}
+
+	if _, err := cache.GetOrParse("c", parse); err != nil || calls["c"] != 2 {
+		t.Errorf("c was parsed %d times, want it evicted by b and parsed again", calls["c"])
+	}
+}
+
+func TestRulesCacheDoesNotCacheErrors(t *testing.T) {
+	calls := map[string]int{}
+	parse := countingParse(calls)
+	cache := NewRulesCache[string](2)
+
+	for range 3 {
+		if _, err := cache.GetOrParse("bad", parse); err == nil {
+			t.Fatal("GetOrParse returned no error for bad rules")
+		}
+	}
+
+	if calls["bad"] != 3 {
+		t.Errorf("bad rules were parsed %d times, want 3", calls["bad"])
+	}
+}
+
+func TestRulesCacheWithoutCapacityAlwaysParses(t *testing.T) {
+	for _, capacity := range []int{0, -1} {
+		calls := map[string]int{}
+		parse := countingParse(calls)
+		cache := NewRulesCache[string](capacity)
+
+		for range 3 {
+			if _, err := cache.GetOrParse("a", parse); err != nil {
+				t.Fatalf("GetOrParse returned %v", err)
+			}
+		}
+
+		if calls["a"] != 3 {
+			t.Errorf("capacity %d: rules were parsed %d times, want 3", capacity, calls["a"])
+		}
+	}
+}
+
+func TestRulesCacheKeysOnTheSources(t *testing.T) {
+	calls := map[string]int{}
+	parse := countingParse(calls)
+	cache := NewRulesCache[string](2)
+
+	for _, sources := range []string{"v1", "v1", "v2"} {
+		if _, err := cache.GetOrParseWithSources("a", sources, parse); err != nil {
+			t.Fatalf("GetOrParseWithSources returned %v", err)
+		}
+	}
+
+	if calls["a"] != 2 {
+		t.Errorf("rules were parsed %d times, want once per sources version", calls["a"])
+	}
+}
+
+func TestCachedVersionRecomputesOnlyAfterTheInterval(t *testing.T) {
+	computed := 0
+	version := newCachedVersion(func() string {
+		computed++
+
+		return fmt.Sprint(computed)
+	}, 5*time.Second)
+
+	now := time.Now()
+	for _, step := range []struct {
+		at   time.Duration
+		want string
+	}{
+		{0, "1"},
+		{time.Second, "1"},
+		{4 * time.Second, "1"},
+		{5 * time.Second, "2"},
+		{9 * time.Second, "2"},
+		{11 * time.Second, "3"},
+	} {
+		if got := version.get(now.Add(step.at)); got != step.want {
+			t.Errorf("version at %v = %s, want %s", step.at, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"fmt"
+)
+
+// rulesMigration upgrades a raw rules document from one version to the next, in place. It reports whether the
+// document used anything of the older version. Documents without "version" may already be in a newer shape, so
+// a migration must leave the parts it does not recognize unchanged.
+type rulesMigration func(doc map[string]interface{}) (bool, error)
+
+// rulesMigrations maps a version to the migration that upgrades it to version+1
+var rulesMigrations = map[int]rulesMigration{}
+
+// registerRulesMigration registers the migration that upgrades rules from version `from` to `from+1`
+func registerRulesMigration(from int, migration rulesMigration) {
+	if _, exists := rulesMigrations[from]; exists {
+		panic(fmt.Sprintf("rules migration from version %d registered twice", from))
+	}
+
+	rulesMigrations[from] = migration
+}
+
+func init() {
+	registerRulesMigration(1, migrateFlatStatistics)
+}
+
+// migrateRules runs every registered migration between the document version and CurrentRulesVersion.
+// It returns the version the document was written in. A document without "version" is migrated from version 1
+// but counts as CurrentRulesVersion when no migration found anything of an older version in it.
+func migrateRules(doc map[string]interface{}) (int, error) {
+	fromVersion, err := rulesVersion(doc)
+	if err != nil {
+		return 0, err
+	}
+	_, explicit := doc["version"]
+	outdated := false
+
+	if fromVersion > CurrentRulesVersion {
+		return fromVersion, fmt.Errorf("rules version %d is newer than the supported version %d", fromVersion, CurrentRulesVersion)
+	}
+
+	for version := fromVersion; version < CurrentRulesVersion; version++ {
+		migration, ok := rulesMigrations[version]
+		if !ok {
+			return fromVersion, fmt.Errorf("no rules migration registered from version %d", version)
+		}
+
+		changed, err := migration(doc)
+		if err != nil {
+			return fromVersion, fmt.Errorf("migrating rules from version %d: %w", version, err)
+		}
+		outdated = outdated || changed
+	}
+
+	doc["version"] = CurrentRulesVersion
+	if !explicit && !outdated {
+		return CurrentRulesVersion, nil
+	}
+
+	return fromVersion, nil
+}
+
+// rulesVersion reads the version field of a raw rules document, defaulting to 1
+func rulesVersion(doc map[string]interface{}) (int, error) {
+	raw, exists := doc["version"]
+	if !exists || raw == nil {
+		return 1, nil
+	}
+
+	version, ok := raw.(float64)
+	if !ok || version < 1 || version != float64(int(version)) {
+		return 0, fmt.Errorf("invalid rules version %v", raw)
+	}
+
+	return int(version), nil
+}
+
+// migrateFlatStatistics turns the version 1 flat list of stat codes into version 2 stat objects:
+// {"statistics": ["mmr_ryu"]} becomes {"statistics": [{"code": "mmr_ryu"}]}. Stat objects are kept as they are.
+func migrateFlatStatistics(doc map[string]interface{}) (bool, error) {
+	config, ok := doc["statistics_config"].(map[string]interface{})
+	if !ok {
+		return false, nil
+	}
+
+	statistics, ok := config["statistics"].([]interface{})
+	if !ok {
+		return false, nil
+	}
+
+	changed := false
+	for i, stat := range statistics {
+		switch stat := stat.(type) {
+		case string:
+			statistics[i] = map[string]interface{}{"code": stat}
+			changed = true
+		case map[string]interface{}:
+		default:
+			return false, fmt.Errorf("statistics_config.statistics[%d]: expected a stat code or a stat object, got %T", i, stat)
+		}
+	}
+
+	return changed, nil
+}
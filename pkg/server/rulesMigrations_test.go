@@ -0,0 +1,123 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testBaseRulesets serves base rulesets from memory
+type testBaseRulesets map[string]string
+
+func (b testBaseRulesets) LoadBaseRuleset(name string) ([]byte, error) {
+	data, ok := b[name]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+
+	return []byte(data), nil
+}
+
+func TestParseGameRulesMigrations(t *testing.T) {
+	bases := testBaseRulesets{
+		"flat":        `{"version": 1, "statistics_config": {"statistics": ["mmr_ryu", "mmr_ken"]}}`,
+		"objects":     `{"version": 2, "statistics_config": {"statistics": [{"code": "mmr_ryu"}]}}`,
+		"unversioned": `{"statistics_config": {"statistics": [{"code": "mmr_ryu"}]}}`,
+	}
+
+	tests := []struct {
+		name        string
+		json        string
+		wantVersion int
+		wantStats   string
+		wantErr     string
+	}{
+		{
+			name:        "version 1 stat codes",
+			json:        `{"version": 1, "statistics_config": {"statistics": ["mmr_ryu"]}}`,
+			wantVersion: 1,
+			wantStats:   "[mmr_ryu]",
+		},
+		{
+			name:        "missing version with stat codes",
+			json:        `{"statistics_config": {"statistics": ["mmr_ryu"]}}`,
+			wantVersion: 1,
+			wantStats:   "[mmr_ryu]",
+		},
+		{
+			name:        "missing version in the current shape",
+			json:        `{"statistics_config": {"statistics": [{"code": "mmr_ryu"}]}}`,
+			wantVersion: CurrentRulesVersion,
+			wantStats:   "[mmr_ryu]",
+		},
+		{
+			name:        "missing version mixing stat codes and objects",
+			json:        `{"statistics_config": {"statistics": ["mmr_ryu", {"code": "mmr_ken"}]}}`,
+			wantVersion: 1,
+			wantStats:   "[mmr_ryu mmr_ken]",
+		},
+		{
+			name:        "current version over a version 1 base",
+			json:        `{"version": 2, "extends": "flat", "auto_backfill": true}`,
+			wantVersion: 1,
+			wantStats:   "[mmr_ryu mmr_ken]",
+		},
+		{
+			name:        "version 1 over a current base",
+			json:        `{"version": 1, "extends": "objects", "statistics_config": {"statistics": ["mmr_ken"]}}`,
+			wantVersion: 1,
+			wantStats:   "[mmr_ken]",
+		},
+		{
+			name:        "missing version over a current base",
+			json:        `{"extends": "objects", "auto_backfill": true}`,
+			wantVersion: CurrentRulesVersion,
+			wantStats:   "[mmr_ryu]",
+		},
+		{
+			name:        "missing version over an unversioned current base",
+			json:        `{"extends": "unversioned"}`,
+			wantVersion: CurrentRulesVersion,
+			wantStats:   "[mmr_ryu]",
+		},
+		{
+			name:    "stat that is neither a code nor an object",
+			json:    `{"statistics_config": {"statistics": [7]}}`,
+			wantErr: "expected a stat code or a stat object",
+		},
+		{
+			name:    "version newer than supported",
+			json:    fmt.Sprintf(`{"version": %d}`, CurrentRulesVersion+1),
+			wantErr: "is newer than the supported version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, version, err := ParseGameRulesStrict(tt.json, bases)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if version != tt.wantVersion {
+				t.Errorf("version = %d, want %d", version, tt.wantVersion)
+			}
+
+			if stats := fmt.Sprint(rules.Statistics.StatCodes()); stats != tt.wantStats {
+				t.Errorf("stat codes = %s, want %s", stats, tt.wantStats)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// BaseRulesets loads the named base rulesets a ruleset can extend with "extends"
+type BaseRulesets interface {
+	LoadBaseRuleset(name string) ([]byte, error)
+}
+
+// RulesDirectory loads base rulesets from <directory>/<name>.json
+type RulesDirectory string
+
+// LoadBaseRuleset reads the base ruleset file for name
+func (d RulesDirectory) LoadBaseRuleset(name string) ([]byte, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return nil, fmt.Errorf("invalid base ruleset name %q", name)
+	}
+
+	return os.ReadFile(filepath.Join(string(d), name+".json"))
+}
+
+// Version returns a fingerprint of the base ruleset files made of their names, sizes and modification times,
+// it changes whenever a file is added, removed or edited. It is empty when the directory cannot be read.
+func (d RulesDirectory) Version() string {
+	entries, err := os.ReadDir(string(d))
+	if err != nil {
+		return ""
+	}
+
+	var version strings.Builder
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		fmt.Fprintf(&version, "%s:%d:%d;", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+
+	return version.String()
+}
+
+// ParseGameRules unmarshals jsonRules, resolving "extends" against bases and migrating every
+// document to CurrentRulesVersion. It also returns the oldest version found in the inheritance chain.
+// bases may be nil when inheritance is not used. Unknown fields are ignored and the rules are not validated.
+func ParseGameRules(jsonRules string, bases BaseRulesets) (GameRules, int, error) {
+	return parseGameRules(jsonRules, bases, false)
+}
+
+// ParseGameRulesStrict is ParseGameRules rejecting the fields GameRules does not know, such as misspelled keys.
+// The error lists every unknown field and every field of the wrong type.
+func ParseGameRulesStrict(jsonRules string, bases BaseRulesets) (GameRules, int, error) {
+	return parseGameRules(jsonRules, bases, true)
+}
+
+// CheckGameRules returns every problem of a ruleset: the unknown fields, the fields of the wrong type and the
+// problems found by GameRules.Validate. It also returns the oldest version found in the inheritance chain.
+// A ruleset that is not valid JSON or whose inheritance cannot be resolved only reports that problem, with a
+// version of 0.
+func CheckGameRules(jsonRules string, bases BaseRulesets) (int, []error) {
+	doc, fromVersion, err := resolveRules([]byte(jsonRules), bases, nil)
+	if err != nil {
+		return 0, []error{err}
+	}
+
+	problems := decodeProblems(doc, reflect.TypeOf(GameRules{}), "")
+
+	// the fields of the wrong type are left empty, the rest is still validated
+	ruleSet, _ := decodeRules(doc, false)
+	problems = append(problems, ruleSet.Validate()...)
+
+	return fromVersion, problems
+}
+
+func parseGameRules(jsonRules string, bases BaseRulesets, strict bool) (GameRules, int, error) {
+	doc, fromVersion, err := resolveRules([]byte(jsonRules), bases, nil)
+	if err != nil {
+		return GameRules{}, fromVersion, err
+	}
+
+	if strict {
+		if problems := decodeProblems(doc, reflect.TypeOf(GameRules{}), ""); len(problems) > 0 {
+			return GameRules{}, fromVersion, errors.Join(problems...)
+		}
+	}
+
+	ruleSet, err := decodeRules(doc, strict)
+	if err != nil {
+		return GameRules{}, fromVersion, err
+	}
+
+	return ruleSet, fromVersion, nil
+}
+
+// decodeRules decodes a resolved rules document and applies the defaults. A field of the wrong type is left
+// empty and reported in the error.
+func decodeRules(doc map[string]interface{}, strict bool) (GameRules, error) {
+	resolved, err := json.Marshal(doc)
+	if err != nil {
+		return GameRules{}, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(resolved))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+
+	var ruleSet GameRules
+	err = decoder.Decode(&ruleSet)
+	ruleSet.applyDefaults()
+
+	return ruleSet, err
+}
+
+// decodeProblems returns the unknown fields of value and the fields that do not decode into their type t.
+// path is the location of value in the rules document.
+func decodeProblems(value interface{}, t reflect.Type, path string) []error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if value == nil || t == rawMessageType {
+		return nil
+	}
+
+	var problems []error
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return []error{fmt.Errorf("%s: expected an object, got %s", fieldPath(path), jsonKind(value))}
+		}
+
+		fields := make(map[string]reflect.Type, t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			if name, ok := jsonFieldName(t.Field(i)); ok {
+				fields[name] = t.Field(i).Type
+			}
+		}
+
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			fieldType, known := fields[key]
+			if !known {
+				problems = append(problems, fmt.Errorf("%s: unknown field", joinPath(path, key)))
+
+				continue
+			}
+			problems = append(problems, decodeProblems(object[key], fieldType, joinPath(path, key))...)
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := value.([]interface{})
+		if !ok {
+			return []error{fmt.Errorf("%s: expected an array, got %s", fieldPath(path), jsonKind(value))}
+		}
+
+		for i, item := range items {
+			problems = append(problems, decodeProblems(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return []error{fmt.Errorf("%s: expected an object, got %s", fieldPath(path), jsonKind(value))}
+		}
+
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			problems = append(problems, decodeProblems(object[key], t.Elem(), joinPath(path, key))...)
+		}
+	default:
+		data, _ := json.Marshal(value)
+		if err := json.Unmarshal(data, reflect.New(t).Interface()); err != nil {
+			problems = append(problems, fmt.Errorf("%s: expected %s, got %s", fieldPath(path), t.Kind(), jsonKind(value)))
+		}
+	}
+
+	return problems
+}
+
+// joinPath returns the path of the field key of the object at path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+// fieldPath returns path, or a name for the whole document when path is empty
+func fieldPath(path string) string {
+	if path == "" {
+		return "rules"
+	}
+
+	return path
+}
+
+// jsonKind names the JSON type of a decoded value
+func jsonKind(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	default:
+		return "null"
+	}
+}
+
+// resolveRules returns the migrated document deep-merged over its base rulesets, if any.
+// chain holds the base names currently being resolved and is used to detect cycles.
+func resolveRules(data []byte, bases BaseRulesets, chain []string) (map[string]interface{}, int, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, 0, err
+	}
+
+	fromVersion, err := migrateRules(doc)
+	if err != nil {
+		return nil, fromVersion, err
+	}
+
+	rawExtends, exists := doc["extends"]
+	if !exists || rawExtends == nil {
+		return doc, fromVersion, nil
+	}
+	delete(doc, "extends")
+
+	baseName, ok := rawExtends.(string)
+	if !ok {
+		return nil, fromVersion, fmt.Errorf("extends: expected a base ruleset name, got %T", rawExtends)
+	}
+
+	for _, name := range chain {
+		if name == baseName {
+			return nil, fromVersion, fmt.Errorf("extends cycle: %s -> %s", strings.Join(chain, " -> "), baseName)
+		}
+	}
+
+	if bases == nil {
+		return nil, fromVersion, fmt.Errorf("extends %q: no base rulesets are configured", baseName)
+	}
+
+	baseData, err := bases.LoadBaseRuleset(baseName)
+	if err != nil {
+		return nil, fromVersion, fmt.Errorf("extends %q: %w", baseName, err)
+	}
+
+	base, baseVersion, err := resolveRules(baseData, bases, append(chain, baseName))
+	if err != nil {
+		return nil, fromVersion, fmt.Errorf("base ruleset %q: %w", baseName, err)
+	}
+
+	return mergeRules(base, doc), min(fromVersion, baseVersion), nil
+}
+
+// mergeRules deep-merges override onto base: objects are merged key by key, any other value
+// (including arrays) in override replaces the base value
+func mergeRules(base, override map[string]interface{}) map[string]interface{} {
+	for key, value := range override {
+		overrideObject, isObject := value.(map[string]interface{})
+		baseObject, baseIsObject := base[key].(map[string]interface{})
+
+		if isObject && baseIsObject {
+			base[key] = mergeRules(baseObject, overrideObject)
+		} else {
+			base[key] = value
+		}
+	}
+
+	return base
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCheckGameRulesReportsEveryProblem(t *testing.T) {
+	json := `{
+		"version": 2,
+		"alliance": {"max_number": "two", "min_number": 1, "player_max_number": 1, "player_min_nmber": 1},
+		"matching_rule": [{"attribute": "mmr", "reference": 100, "max": 3000}],
+		"team_balance": {"strategy": "weird"}
+	}`
+
+	_, problems := CheckGameRules(json, nil)
+
+	var got []string
+	for _, problem := range problems {
+		got = append(got, problem.Error())
+	}
+
+	for _, want := range []string{
+		"alliance.max_number: expected int, got a string",
+		"alliance.player_min_nmber: unknown field",
+		"matching_rule[0].max: unknown field",
+		`team_balance.strategy: unsupported strategy "weird"`,
+	} {
+		found := false
+		for _, problem := range got {
+			found = found || strings.Contains(problem, want)
+		}
+		if !found {
+			t.Errorf("problems %q do not report %q", got, want)
+		}
+	}
+
+	if _, _, err := ParseGameRulesStrict(json, nil); err == nil || !strings.Contains(err.Error(), "player_min_nmber") || !strings.Contains(err.Error(), "matching_rule[0].max") {
+		t.Errorf("ParseGameRulesStrict error = %v, want every unknown field", err)
+	}
+
+	if _, _, err := ParseGameRules(json, nil); err == nil {
+		t.Error("ParseGameRules accepted a field of the wrong type")
+	}
+}
+
+func TestValidateRejectsStatisticsKeysThatAreStatCodes(t *testing.T) {
+	rules := GameRules{Statistics: StatisticsConfig{
+		Statistics:      []StatDefinition{{Code: "mmr_ryu"}, {Code: "mmr_ken"}},
+		SelectedStatKey: "mmr_ken",
+		EnrichedKey:     "mmr_ryu",
+	}}
+	rules.Alliance = AllianceRule{MinNumber: 2, MaxNumber: 2, PlayerMinNumber: 1, PlayerMaxNumber: 1}
+
+	var got []string
+	for _, problem := range rules.Validate() {
+		got = append(got, problem.Error())
+	}
+
+	for _, want := range []string{
+		`statistics_config.enriched_key: "mmr_ryu" must not be one of the configured stat codes`,
+		`statistics_config.selected_stat_key: "mmr_ken" must not be one of the configured stat codes`,
+	} {
+		if !slices.Contains(got, want) {
+			t.Errorf("problems %q do not report %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"matchmaking-function-grpc-plugin-server-go/pkg/matchmaker"
+)
+
+// serverProviderAMS selects AMS fleets through claim keys, an empty provider selects a DS Armada deployment
+const serverProviderAMS = "AMS"
+
+// stringTicketAttribute returns a string ticket attribute, or "" when key is empty or the attribute is missing
+func stringTicketAttribute(ticket matchmaker.Ticket, key string) string {
+	if key == "" {
+		return ""
+	}
+
+	value, _ := ticket.TicketAttributes[key].(string)
+
+	return value
+}
+
+// serverPoolSelection returns the server pool of a match from the rules defaults, overridden by the entry of
+// its preferred region and then by the entry of its client version
+func serverPoolSelection(rule ServerSelectionRule, region, clientVersion string) matchmaker.ServerPoolSelectionParameter {
+	pool := matchmaker.ServerPoolSelectionParameter{
+		ServerProvider: rule.Provider,
+		Deployment:     rule.Deployment,
+		ClaimKeys:      rule.ClaimKeys,
+	}
+
+	for _, override := range []ServerPool{rule.Regions[region], rule.ClientVersions[clientVersion]} {
+		if override.Deployment != "" {
+			pool.Deployment = override.Deployment
+		}
+		if len(override.ClaimKeys) > 0 {
+			pool.ClaimKeys = override.ClaimKeys
+		}
+	}
+
+	return pool
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"matchmaking-function-grpc-plugin-server-go/pkg/common"
+	"matchmaking-function-grpc-plugin-server-go/pkg/matchmaker"
+)
+
+const (
+	strategyMMRWindow = "mmr_window"
+	strategyRoleQueue = "role_queue"
+)
+
+// MatchingStrategy makes the matches of a tick. A ruleset selects one by name with "strategy" and
+// configures it with "strategy_options".
+type MatchingStrategy interface {
+	// Validate returns the problems of the rules for this strategy, each prefixed with the rules path
+	Validate(rules GameRules) []error
+
+	// MakeMatches posts the matches made from tickets on results until it is done or scope.Ctx is.
+	// history holds the recent matches, it may be nil.
+	MakeMatches(scope *common.Scope, tickets []matchmaker.Ticket, rules GameRules, history *MatchHistory, results chan<- matchmaker.Match)
+}
+
+// StrategyFactory builds a strategy from the "strategy_options" of a ruleset, options is empty when not set
+type StrategyFactory func(options json.RawMessage) (MatchingStrategy, error)
+
+var (
+	strategiesMu sync.RWMutex
+	strategies   = map[string]StrategyFactory{}
+)
+
+// RegisterStrategy makes a strategy available to rulesets under name. It panics when name is empty or
+// already registered, strategies are expected to register from init functions.
+func RegisterStrategy(name string, factory StrategyFactory) {
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+
+	if name == "" || factory == nil {
+		panic("matching strategy registered without a name or factory")
+	}
+	if _, exists := strategies[name]; exists {
+		panic(fmt.Sprintf("matching strategy %q registered twice", name))
+	}
+
+	strategies[name] = factory
+}
+
+// StrategyNames returns the names of the registered strategies in name order
+func StrategyNames() []string {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// newStrategy builds the named strategy with its options, errors are prefixed with the rules path
+func newStrategy(name string, options json.RawMessage) (MatchingStrategy, error) {
+	strategiesMu.RLock()
+	factory, ok := strategies[name]
+	strategiesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("strategy: unknown strategy %q, registered strategies are %v", name, StrategyNames())
+	}
+
+	strategy, err := factory(options)
+	if err != nil {
+		return nil, fmt.Errorf("strategy_options: %w", err)
+	}
+
+	return strategy, nil
+}
+
+// DecodeStrategyOptions unmarshals strategy options into target, rejecting unknown fields.
+// Empty or null options leave target unchanged.
+func DecodeStrategyOptions(options json.RawMessage, target interface{}) error {
+	if len(bytes.TrimSpace(options)) == 0 || bytes.Equal(bytes.TrimSpace(options), []byte("null")) {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(options))
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(target)
+}
+
+func init() {
+	RegisterStrategy(strategyMMRWindow, newMMRWindowStrategy)
+	RegisterStrategy(strategyRoleQueue, newRoleQueueStrategy)
+}
+
+// mmrWindowStrategy groups tickets within the matching distance of each other, see makeMMRWindowMatches
+type mmrWindowStrategy struct{}
+
+func newMMRWindowStrategy(options json.RawMessage) (MatchingStrategy, error) {
+	return mmrWindowStrategy{}, DecodeStrategyOptions(options, &struct{}{})
+}
+
+func (mmrWindowStrategy) Validate(GameRules) []error {
+	return nil
+}
+
+func (mmrWindowStrategy) MakeMatches(scope *common.Scope, tickets []matchmaker.Ticket, rules GameRules, history *MatchHistory, results chan<- matchmaker.Match) {
+	makeMMRWindowMatches(scope, tickets, rules, history, results)
+}
+
+// roleQueueStrategy is the MMR window strategy for rulesets that require a role composition, it can put
+// filling the roles before skill
+type roleQueueStrategy struct {
+	// IgnoreSkill drops the matching distance, only roles and the other constraints decide the groups
+	IgnoreSkill bool `json:"ignore_skill"`
+}
+
+func newRoleQueueStrategy(options json.RawMessage) (MatchingStrategy, error) {
+	var strategy roleQueueStrategy
+
+	return strategy, DecodeStrategyOptions(options, &strategy)
+}
+
+func (s roleQueueStrategy) Validate(rules GameRules) []error {
+	if !rules.Roles.IsConfigured() {
+		return []error{fmt.Errorf("roles.composition: required by the %s strategy", strategyRoleQueue)}
+	}
+
+	return nil
+}
+
+func (s roleQueueStrategy) MakeMatches(scope *common.Scope, tickets []matchmaker.Ticket, rules GameRules, history *MatchHistory, results chan<- matchmaker.Match) {
+	if s.IgnoreSkill {
+		rules.MatchingRules, rules.FlexingRules = nil, nil
+	}
+
+	makeMMRWindowMatches(scope, tickets, rules, history, results)
+}
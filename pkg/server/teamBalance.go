@@ -0,0 +1,251 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"math"
+	"sort"
+
+	"matchmaking-function-grpc-plugin-server-go/pkg/common"
+)
+
+const (
+	balanceStrategySnake      = "snake"
+	balanceStrategyExhaustive = "exhaustive"
+	balanceStrategyNone       = "none"
+
+	balanceMetricAverage = "average"
+	balanceMetricSum     = "sum"
+
+	// maxExhaustiveTickets bounds exhaustive_max_tickets, the number of splits grows exponentially with tickets
+	maxExhaustiveTickets = 12
+
+	// exhaustiveCheckInterval is how many search steps the exhaustive strategy takes between cancellation checks
+	exhaustiveCheckInterval = 1024
+)
+
+// assignTeams splits the members of a match across teams following the balance rule. Tickets, and so
+// parties, are never split. It falls back to packing teams in order, or to the first split following the
+// team constraints of the rules, when the balanced split is not valid, and returns false when the members do
+// not fit the teams at all. Penalized mirror matches are avoided when another split is possible. An
+// exhaustive search cancelled through scope.Ctx keeps the best split found so far.
+func assignTeams(scope *common.Scope, members []matchCandidate, alliance AllianceRule, rules GameRules) ([][]int, bool) {
+	if rules.Characters.GetMirrorMatch() == mirrorMatchPenalize {
+		if teams, ok := splitTeams(scope, members, alliance, rules.TeamBalance, newTeamConstraints(rules, true)); ok {
+			return teams, true
+		}
+	}
+
+	return splitTeams(scope, members, alliance, rules.TeamBalance, newTeamConstraints(rules, false))
+}
+
+func splitTeams(scope *common.Scope, members []matchCandidate, alliance AllianceRule, balance TeamBalanceRule, constraints teamConstraints) ([][]int, bool) {
+	valid := func(teams [][]int) bool {
+		return meetsAllianceMinimum(members, teams, alliance)
+	}
+
+	fallback, ok := packTeams(members, alliance)
+	if constraints.active() {
+		valid = func(teams [][]int) bool {
+			return meetsAllianceMinimum(members, teams, alliance) && constraints.valid(members, teams)
+		}
+		fallback = constrainedTeams(members, alliance, constraints, true)
+		ok = fallback != nil
+	}
+
+	if !ok {
+		return nil, false
+	}
+
+	var balanced [][]int
+	switch balance.GetStrategy() {
+	case balanceStrategyNone:
+		return fallback, true
+	case balanceStrategyExhaustive:
+		if len(members) <= min(balance.GetExhaustiveMaxTickets(), maxExhaustiveTickets) {
+			balanced = exhaustiveTeams(scope, members, alliance, balance.GetMetric(), valid)
+		} else {
+			balanced = snakeTeams(members, alliance)
+		}
+	default:
+		balanced = snakeTeams(members, alliance)
+	}
+
+	if balanced == nil || !valid(balanced) {
+		return fallback, true
+	}
+
+	return balanced, true
+}
+
+// balancedTeamCount returns how many teams the players are spread over: enough to hold them all, at least
+// the alliance minimum and at most the alliance maximum
+func balancedTeamCount(players int, alliance AllianceRule) int {
+	count := (players + alliance.PlayerMaxNumber - 1) / alliance.PlayerMaxNumber
+
+	return max(min(max(count, alliance.MinNumber), alliance.MaxNumber), 1)
+}
+
+// snakeTeams drafts tickets onto teams in serpentine order, highest value first: the teams pick in order
+// 1..n, then n..1, and so on. Larger tickets are drafted first so parties are not left without a seat. A team
+// that cannot seat the drafted ticket keeps its turn and the ticket goes to the next team of the draft order
+// with room.
+func snakeTeams(members []matchCandidate, alliance AllianceRule) [][]int {
+	order := make([]int, len(members))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := members[order[i]], members[order[j]]
+		if a.players() != b.players() {
+			return a.players() > b.players()
+		}
+
+		return a.value > b.value
+	})
+
+	players := 0
+	for _, member := range members {
+		players += member.players()
+	}
+
+	teams := make([][]int, balancedTeamCount(players, alliance))
+	sizes := make([]int, len(teams))
+
+	// pickTeam returns the team picking at position pick of the serpentine draft order
+	pickTeam := func(pick int) int {
+		round, turn := pick/len(teams), pick%len(teams)
+		if round%2 == 1 {
+			return len(teams) - 1 - turn
+		}
+
+		return turn
+	}
+
+	pick := 0
+	for _, member := range order {
+		// full teams give up their turns
+		for skipped := 0; skipped < 2*len(teams) && sizes[pickTeam(pick)] >= alliance.PlayerMaxNumber; skipped++ {
+			pick++
+		}
+
+		best := -1
+		for next := pick; next < pick+2*len(teams); next++ {
+			if team := pickTeam(next); sizes[team]+members[member].players() <= alliance.PlayerMaxNumber {
+				best = team
+
+				break
+			}
+		}
+
+		if best < 0 {
+			return nil
+		}
+		if best == pickTeam(pick) {
+			pick++
+		}
+
+		teams[best] = append(teams[best], member)
+		sizes[best] += members[member].players()
+	}
+
+	return teams
+}
+
+// exhaustiveTeams tries every split of the tickets across teams and returns the valid one with the lowest
+// imbalance, or nil when there is none. Once scope.Ctx is done it stops and returns the best split found so far.
+func exhaustiveTeams(scope *common.Scope, members []matchCandidate, alliance AllianceRule, metric string, valid func([][]int) bool) [][]int {
+	players := 0
+	for _, member := range members {
+		players += member.players()
+	}
+
+	teamCount := balancedTeamCount(players, alliance)
+	assignment := make([]int, len(members))
+	sizes := make([]int, teamCount)
+
+	var best [][]int
+	bestImbalance := math.Inf(1)
+	steps, cancelled := 0, false
+
+	var search func(member, usedTeams int)
+	search = func(member, usedTeams int) {
+		if steps%exhaustiveCheckInterval == 0 && scope.Ctx.Err() != nil {
+			cancelled = true
+		}
+		steps++
+
+		if cancelled {
+			return
+		}
+
+		if member == len(members) {
+			teams := make([][]int, teamCount)
+			for i, team := range assignment {
+				teams[team] = append(teams[team], i)
+			}
+
+			if !valid(teams) {
+				return
+			}
+
+			if imbalance := teamImbalance(members, teams, metric); imbalance < bestImbalance {
+				best, bestImbalance = teams, imbalance
+			}
+
+			return
+		}
+
+		// teams are interchangeable, so a ticket only opens the next unused team
+		for team := 0; team < min(usedTeams+1, teamCount); team++ {
+			if sizes[team]+members[member].players() > alliance.PlayerMaxNumber {
+				continue
+			}
+
+			assignment[member] = team
+			sizes[team] += members[member].players()
+			search(member+1, max(usedTeams, team+1))
+			sizes[team] -= members[member].players()
+		}
+	}
+	search(0, 0)
+
+	return best
+}
+
+// teamValue returns the balanced value of a team from the sum of its players' values
+func teamValue(total float64, players int, metric string) float64 {
+	if metric == balanceMetricSum || players == 0 {
+		return total
+	}
+
+	return total / float64(players)
+}
+
+// teamImbalance returns the difference between the highest and the lowest value of the non-empty teams
+func teamImbalance(members []matchCandidate, teams [][]int, metric string) float64 {
+	highest, lowest := math.Inf(-1), math.Inf(1)
+
+	for _, team := range teams {
+		if len(team) == 0 {
+			continue
+		}
+
+		total, players := 0.0, 0
+		for _, member := range team {
+			total += members[member].value * float64(members[member].players())
+			players += members[member].players()
+		}
+
+		value := teamValue(total, players, metric)
+		highest, lowest = math.Max(highest, value), math.Min(lowest, value)
+	}
+
+	if math.IsInf(highest, -1) {
+		return 0
+	}
+
+	return highest - lowest
+}
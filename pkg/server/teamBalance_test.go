@@ -0,0 +1,100 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"matchmaking-function-grpc-plugin-server-go/pkg/matchmaker"
+	"matchmaking-function-grpc-plugin-server-go/pkg/playerdata"
+)
+
+// testCandidate returns a candidate with players players, all valued at value
+func testCandidate(value float64, players int) matchCandidate {
+	ticket := matchmaker.Ticket{TicketID: fmt.Sprintf("ticket-%v-%d", value, players)}
+	for i := range players {
+		ticket.Players = append(ticket.Players, playerdata.PlayerData{PlayerID: playerdata.IDFromString(fmt.Sprintf("%s-player-%d", ticket.TicketID, i))})
+	}
+
+	return matchCandidate{ticket: ticket, value: value}
+}
+
+// TestSnakeTeamsImbalanceIsBounded drafts solos onto two teams of equal size. The serpentine draft keeps the
+// difference of the team totals within the spread of the values, so the averages are at most the spread over
+// the team size apart, and the exhaustive split is never worse.
+func TestSnakeTeamsImbalanceIsBounded(t *testing.T) {
+	for seed := range int64(200) {
+		random := rand.New(rand.NewSource(seed))
+		teamSize := 1 + random.Intn(5)
+		alliance := AllianceRule{MinNumber: 2, MaxNumber: 2, PlayerMinNumber: teamSize, PlayerMaxNumber: teamSize}
+
+		var members []matchCandidate
+		highest, lowest := math.Inf(-1), math.Inf(1)
+		for range 2 * teamSize {
+			value := math.Round(1000 + random.NormFloat64()*300)
+			members = append(members, testCandidate(value, 1))
+			highest, lowest = math.Max(highest, value), math.Min(lowest, value)
+		}
+
+		valid := func(teams [][]int) bool {
+			return meetsAllianceMinimum(members, teams, alliance)
+		}
+
+		snake := snakeTeams(members, alliance)
+		exhaustive := exhaustiveTeams(testScope(), members, alliance, balanceMetricAverage, valid)
+		if snake == nil || !valid(snake) || exhaustive == nil {
+			t.Fatalf("seed %d: no valid split: snake %v, exhaustive %v", seed, snake, exhaustive)
+		}
+
+		got := teamImbalance(members, snake, balanceMetricAverage)
+		if bound := (highest - lowest) / float64(teamSize); got > bound+1e-9 {
+			t.Errorf("seed %d: snake imbalance = %v (teams %v), more than %v", seed, got, snake, bound)
+		}
+		if best := teamImbalance(members, exhaustive, balanceMetricAverage); best > got+1e-9 {
+			t.Errorf("seed %d: exhaustive imbalance = %v, worse than snake imbalance %v", seed, best, got)
+		}
+	}
+}
+
+func TestExhaustiveTeamsStopsWhenCancelled(t *testing.T) {
+	alliance := AllianceRule{MinNumber: 2, MaxNumber: 4, PlayerMinNumber: 1, PlayerMaxNumber: 3}
+
+	var members []matchCandidate
+	for i := range maxExhaustiveTickets {
+		members = append(members, testCandidate(float64(1000+i), 1))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	scope := testScope()
+	scope.Ctx = ctx
+
+	valid := func([][]int) bool {
+		return true
+	}
+
+	if teams := exhaustiveTeams(scope, members, alliance, balanceMetricAverage, valid); teams != nil {
+		t.Errorf("cancelled search returned %v, want nil", teams)
+	}
+
+	rules := GameRules{Alliance: alliance, TeamBalance: TeamBalanceRule{Strategy: balanceStrategyExhaustive, ExhaustiveMaxTickets: maxExhaustiveTickets}}
+	if teams, ok := assignTeams(scope, members, alliance, rules); !ok || !meetsAllianceMinimum(members, teams, alliance) {
+		t.Errorf("cancelled assignTeams returned %v, %v, want the fallback split", teams, ok)
+	}
+}
+
+func TestTeamBalanceRuleLimitsExhaustiveMaxTickets(t *testing.T) {
+	if problems := (TeamBalanceRule{ExhaustiveMaxTickets: maxExhaustiveTickets}).validate(); len(problems) != 0 {
+		t.Errorf("exhaustive_max_tickets of %d rejected: %v", maxExhaustiveTickets, problems)
+	}
+
+	if problems := (TeamBalanceRule{ExhaustiveMaxTickets: maxExhaustiveTickets + 1}).validate(); len(problems) != 1 {
+		t.Errorf("exhaustive_max_tickets of %d gave problems %v, want 1", maxExhaustiveTickets+1, problems)
+	}
+}
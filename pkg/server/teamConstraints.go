@@ -0,0 +1,128 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"sort"
+)
+
+// teamConstraints are the rules a split of tickets across teams must follow besides the team sizes
+type teamConstraints struct {
+	roles         RoleRule
+	uniquePerTeam bool
+	forbidMirror  bool
+}
+
+// newTeamConstraints returns the team constraints of the rules. Penalized mirror matches are forbidden
+// when preferMirrorFree is set, so callers can try a mirror-free split first.
+func newTeamConstraints(rules GameRules, preferMirrorFree bool) teamConstraints {
+	mirror := rules.Characters.GetMirrorMatch()
+
+	return teamConstraints{
+		roles:         rules.Roles,
+		uniquePerTeam: rules.Characters.UniquePerTeam,
+		forbidMirror:  mirror == mirrorMatchForbid || preferMirrorFree && mirror == mirrorMatchPenalize,
+	}
+}
+
+// active reports whether there is any constraint to check
+func (c teamConstraints) active() bool {
+	return c.roles.IsConfigured() || c.uniquePerTeam || c.forbidMirror
+}
+
+// teamFits reports whether teams[team] can still be completed: its players can take distinct roles, its
+// characters are unique when required and none of them is played on another team when mirrors are forbidden
+func (c teamConstraints) teamFits(members []matchCandidate, teams [][]int, team int) bool {
+	if c.roles.IsConfigured() {
+		if _, ok := assignRoles(teamPlayerRoles(members, teams[team]), c.roles.Composition); !ok {
+			return false
+		}
+	}
+
+	if c.uniquePerTeam && hasDuplicateCharacter(teamCharacters(members, teams[team])) {
+		return false
+	}
+
+	return !c.forbidMirror || !mirrorConflict(members, teams, team)
+}
+
+// valid reports whether every team follows the constraints and is full when roles are configured
+func (c teamConstraints) valid(members []matchCandidate, teams [][]int) bool {
+	for team := range teams {
+		if !c.teamFits(members, teams, team) {
+			return false
+		}
+	}
+
+	return !c.roles.IsConfigured() || rolesComplete(members, teams, c.roles)
+}
+
+// constrainedTeams searches a split of the tickets across the alliance teams following the constraints.
+// When complete, the split must also be valid and meet the alliance minimums, otherwise every team only has
+// to remain completable. It returns nil when there is no such split.
+func constrainedTeams(members []matchCandidate, alliance AllianceRule, constraints teamConstraints, complete bool) [][]int {
+	// cheap rejection: all the players must at least fit the roles of every team together
+	if constraints.roles.IsConfigured() {
+		pooled := make(map[string]int, len(constraints.roles.Composition))
+		for role, count := range constraints.roles.Composition {
+			pooled[role] = count * alliance.MaxNumber
+		}
+		if _, ok := assignRoles(teamPlayerRoles(members, allMembers(members)), pooled); !ok {
+			return nil
+		}
+	}
+
+	// larger tickets first, they are the hardest to place
+	order := allMembers(members)
+	sort.SliceStable(order, func(i, j int) bool {
+		return members[order[i]].players() > members[order[j]].players()
+	})
+
+	teams := make([][]int, alliance.MaxNumber)
+	sizes := make([]int, alliance.MaxNumber)
+
+	var search func(next, usedTeams int) bool
+	search = func(next, usedTeams int) bool {
+		if next == len(order) {
+			return !complete || constraints.valid(members, teams) && meetsAllianceMinimum(members, teams, alliance)
+		}
+
+		member := order[next]
+		// teams are interchangeable, so a ticket only opens the next unused team
+		for team := 0; team < min(usedTeams+1, len(teams)); team++ {
+			if sizes[team]+members[member].players() > alliance.PlayerMaxNumber {
+				continue
+			}
+
+			teams[team] = append(teams[team], member)
+			if constraints.teamFits(members, teams, team) {
+				sizes[team] += members[member].players()
+				if search(next+1, max(usedTeams, team+1)) {
+					return true
+				}
+				sizes[team] -= members[member].players()
+			}
+			teams[team] = teams[team][:len(teams[team])-1]
+		}
+
+		return false
+	}
+
+	if !search(0, 0) {
+		return nil
+	}
+
+	return teams
+}
+
+// allMembers returns the indexes of every member
+func allMembers(members []matchCandidate) []int {
+	indexes := make([]int, len(members))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	return indexes
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	matchfunctiongrpc "matchmaking-function-grpc-plugin-server-go/pkg/pb"
+)
+
+// TickCache remembers the matches produced for each (match pool, tickId) for a bounded time, so a retried
+// MakeMatches tick gets the same matches back instead of a new set. A tickId of 0 is never cached.
+// It is safe for concurrent use, a nil TickCache remembers nothing.
+type TickCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	ticks    expiringLRU[[]*matchfunctiongrpc.Match]
+}
+
+// NewTickCache returns a TickCache keeping the matches of at most capacity ticks for ttl.
+// A capacity below 1 or a ttl of 0 returns nil, which disables the cache.
+func NewTickCache(capacity int, ttl time.Duration) *TickCache {
+	if capacity < 1 || ttl <= 0 {
+		return nil
+	}
+
+	return &TickCache{
+		capacity: capacity,
+		ttl:      ttl,
+		ticks:    newExpiringLRU[[]*matchfunctiongrpc.Match](),
+	}
+}
+
+// Get returns the matches produced for the tick of a match pool, if they are still remembered
+func (c *TickCache) Get(matchPool string, tickID uint64) ([]*matchfunctiongrpc.Match, bool) {
+	if c == nil || tickID == 0 {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ticks.get(tickCacheKey(matchPool, tickID), time.Now(), c.ttl)
+}
+
+// Put remembers the matches produced for the tick of a match pool
+func (c *TickCache) Put(matchPool string, tickID uint64, matches []*matchfunctiongrpc.Match) {
+	if c == nil || tickID == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ticks.put(tickCacheKey(matchPool, tickID), matches, time.Now(), c.capacity)
+}
+
+func tickCacheKey(matchPool string, tickID uint64) string {
+	return matchPool + "\x00" + strconv.FormatUint(tickID, 10)
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2025 AccelByte Inc. All Rights Reserved.
+// This is licensed software from AccelByte Inc, for limitations
+// and restrictions contact your company contract manager.
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	matchfunctiongrpc "matchmaking-function-grpc-plugin-server-go/pkg/pb"
+)
+
+// runTick runs a MakeMatches tick of one ticket of matchPool and returns the matches sent
+func runTick(t *testing.T, server *MatchFunctionServer[string], matchPool string, tickID uint64) []*matchfunctiongrpc.MatchResponse {
+	t.Helper()
+
+	stream := tickStream(tickID, matchPool, "a")
+	if err := server.MakeMatches(stream); err != nil {
+		t.Fatalf("MakeMatches returned %v", err)
+	}
+
+	return stream.sent
+}
+
+func TestMakeMatchesReplaysRetriedTicks(t *testing.T) {
+	logic := &countingMatchLogic{matches: 2}
+	server := &MatchFunctionServer[string]{MM: logic, TickCache: NewTickCache(8, time.Minute)}
+
+	first := runTick(t, server, "pool", 7)
+	retried := runTick(t, server, "pool", 7)
+
+	if logic.calls != 1 {
+		t.Errorf("the match logic ran %d times, want the retry replayed", logic.calls)
+	}
+	if len(retried) != 2 {
+		t.Fatalf("retry sent %d matches, want 2", len(retried))
+	}
+	for i := range first {
+		if !proto.Equal(first[i], retried[i]) {
+			t.Errorf("retry sent %v, want %v", retried[i], first[i])
+		}
+	}
+
+	// another tick or another match pool is matched again
+	runTick(t, server, "pool", 8)
+	runTick(t, server, "other", 7)
+	if logic.calls != 3 {
+		t.Errorf("the match logic ran %d times, want 3", logic.calls)
+	}
+}
+
+func TestMakeMatchesDoesNotCacheTickZero(t *testing.T) {
+	logic := &countingMatchLogic{matches: 1}
+	server := &MatchFunctionServer[string]{MM: logic, TickCache: NewTickCache(8, time.Minute)}
+
+	first := runTick(t, server, "pool", 0)
+	second := runTick(t, server, "pool", 0)
+
+	if logic.calls != 2 || proto.Equal(first[0], second[0]) {
+		t.Errorf("the match logic ran %d times, want tick 0 matched again", logic.calls)
+	}
+}
+
+func TestMakeMatchesDoesNotCacheTicksWithoutMatches(t *testing.T) {
+	logic := &countingMatchLogic{}
+	server := &MatchFunctionServer[string]{MM: logic, TickCache: NewTickCache(8, time.Minute)}
+
+	runTick(t, server, "pool", 7)
+	logic.matches = 1
+	retried := runTick(t, server, "pool", 7)
+
+	if logic.calls != 2 || len(retried) != 1 {
+		t.Errorf("the match logic ran %d times and the retry sent %d matches, want the retry matched again", logic.calls, len(retried))
+	}
+}
+
+func TestTickCacheExpiresEntries(t *testing.T) {
+	cache := NewTickCache(8, 50*time.Millisecond)
+	matches := []*matchfunctiongrpc.Match{{}}
+
+	cache.Put("pool", 7, matches)
+	if got, ok := cache.Get("pool", 7); !ok || len(got) != 1 {
+		t.Fatalf("Get = %v, %v right after Put, want the matches", got, ok)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if got, ok := cache.Get("pool", 7); ok {
+		t.Errorf("Get = %v after the TTL, want nothing", got)
+	}
+}
+
+func TestTickCacheEvictsBeyondCapacity(t *testing.T) {
+	cache := NewTickCache(2, time.Minute)
+	for tick := range uint64(3) {
+		cache.Put("pool", tick+1, []*matchfunctiongrpc.Match{{}})
+	}
+
+	if _, ok := cache.Get("pool", 1); ok {
+		t.Error("the oldest tick is still cached beyond the capacity")
+	}
+	if _, ok := cache.Get("pool", 3); !ok {
+		t.Error("the newest tick is not cached")
+	}
+}